@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/audit"
+	"github.com/SKF/go-utility/v2/log"
+	authorizeApi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+	"google.golang.org/grpc"
+)
+
+// WithAudit writes a record of every decision, made by the IsAuthorized*
+// calls, to sink. Each item of IsAuthorizedBulk is recorded separately, and a
+// failed call is recorded with audit.DecisionError. The metadata returned by
+// caller, if not nil, is added to every record. Errors returned by the sink
+// are logged to the logger given with WithLogger, or to log.Base() without
+// one.
+//
+// Only the calls reaching the service are recorded. Decisions served from the
+// cache of a CachingClient, or made by the policy of a FallbackClient, are
+// recorded by those clients when given the sink in their config.
+func WithAudit(sink audit.Sink, caller audit.CallerFunc) Option {
+	return func(o *options) {
+		o.auditSink = sink
+		o.auditCaller = caller
+	}
+}
+
+func auditInterceptor(sink audit.Sink, caller audit.CallerFunc, logger log.Logger) grpc.UnaryClientInterceptor {
+	if logger == nil {
+		logger = log.Base()
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		_, name := splitMethod(method)
+		if !strings.HasPrefix(name, "IsAuthorized") {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		template := audit.Record{
+			Time:    start,
+			Method:  name,
+			Latency: time.Since(start),
+		}
+
+		if caller != nil {
+			template.Caller = caller(ctx)
+		}
+
+		if err != nil {
+			template.Decision = audit.DecisionError
+			template.Error = err.Error()
+		}
+
+		for _, record := range auditRecords(template, req, reply, err) {
+			writeAuditRecord(ctx, logger, sink, record)
+		}
+
+		return err
+	}
+}
+
+// writeAudit writes the record of a decision made without reaching the
+// service, logging the errors of the sink to logger, or to log.Base() if nil.
+func writeAudit(ctx context.Context, logger log.Logger, sink audit.Sink, caller audit.CallerFunc, record audit.Record) {
+	record.Time = time.Now()
+
+	if caller != nil {
+		record.Caller = caller(ctx)
+	}
+
+	if logger == nil {
+		logger = log.Base()
+	}
+
+	writeAuditRecord(ctx, logger, sink, record)
+}
+
+func writeAuditRecord(ctx context.Context, logger log.Logger, sink audit.Sink, record audit.Record) {
+	if err := sink.Write(record); err != nil {
+		logger.WithTracing(ctx).WithError(err).
+			WithField("method", record.Method).
+			Error("failed to write audit record")
+	}
+}
+
+// auditRecords returns the records of the decisions of a call, based on
+// template.
+func auditRecords(template audit.Record, req, reply interface{}, err error) []audit.Record {
+	switch r := req.(type) {
+	case *authorizeApi.IsAuthorizedInput:
+		record := template
+		record.UserID = r.GetUserId()
+		record.Action = r.GetAction()
+		record.Resource = auditResource(r.GetResource())
+
+		if err == nil {
+			switch out := reply.(type) {
+			case *authorizeApi.IsAuthorizedWithReasonOutput:
+				record.Decision, record.Reason = auditDecision(out.GetOk()), out.GetReason()
+			case *authorizeApi.IsAuthorizedOutput:
+				record.Decision = auditDecision(out.GetOk())
+			}
+		}
+
+		return []audit.Record{record}
+	case *authorizeApi.IsAuthorizedBulkInput:
+		template.UserID = r.GetUserId()
+		template.Action = r.GetAction()
+
+		if err != nil {
+			records := make([]audit.Record, len(r.GetResources()))
+			for i, resource := range r.GetResources() {
+				records[i] = template
+				records[i].Resource = auditResource(resource)
+			}
+
+			return records
+		}
+
+		responses := reply.(*authorizeApi.IsAuthorizedBulkOutput).GetResponses()
+		records := make([]audit.Record, len(responses))
+		for i, response := range responses {
+			resource := response.GetResource()
+			if resource == nil && response.GetResourceId() != "" { //nolint: staticcheck
+				resource = &common.Origin{Id: response.GetResourceId()} //nolint: staticcheck
+			}
+
+			// Old servers answer with neither, in the order of the request.
+			if resource == nil && i < len(r.GetResources()) {
+				resource = r.GetResources()[i]
+			}
+
+			records[i] = template
+			records[i].Resource = auditResource(resource)
+			records[i].Decision = auditDecision(response.GetOk())
+		}
+
+		return records
+	case *authorizeApi.IsAuthorizedByEndpointInput:
+		record := template
+		record.UserID = r.GetUserId()
+		record.Endpoint = strings.Join([]string{r.GetApi(), r.GetMethod(), r.GetEndpoint()}, " ")
+
+		if out, ok := reply.(*authorizeApi.IsAuthorizedByEndpointOutput); ok && err == nil {
+			record.Decision = auditDecision(out.GetOk())
+		}
+
+		return []audit.Record{record}
+	}
+
+	return nil
+}
+
+func auditResource(resource *common.Origin) *audit.Resource {
+	if resource == nil {
+		return nil
+	}
+
+	return &audit.Resource{
+		ID:       resource.GetId(),
+		Type:     resource.GetType(),
+		Provider: resource.GetProvider(),
+	}
+}
+
+func auditDecision(ok bool) string {
+	if ok {
+		return audit.DecisionAllow
+	}
+
+	return audit.DecisionDeny
+}
@@ -0,0 +1,109 @@
+// Package audit records the authorization decisions acted on by a client.
+//
+// The client emits a Record for every IsAuthorized* call, and for every item
+// of IsAuthorizedBulk, to a Sink given with client.WithAudit. Decisions made
+// without calling the service, by client.CachingClient and
+// client.FallbackClient, are emitted by those clients to the Sink given in
+// their config.
+//
+// Sinks write the records to a zap logger, as JSON lines to a writer, or
+// asynchronously through a buffered channel to another sink, and Redacted
+// hides the user IDs of the records before they are written.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Decisions of a record.
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+	// DecisionError is recorded when the call failed, in which case no
+	// decision was made by the service.
+	DecisionError = "error"
+)
+
+// Resource is the origin a decision was made for.
+type Resource struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// Record is a single authorization decision.
+type Record struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	UserID string    `json:"user_id"`
+	Action string    `json:"action,omitempty"`
+	// Resource is nil for decisions made by endpoint.
+	Resource *Resource `json:"resource,omitempty"`
+	// Endpoint is the API, method and endpoint of decisions made by
+	// endpoint.
+	Endpoint string `json:"endpoint,omitempty"`
+	Decision string `json:"decision"`
+	Reason   string `json:"reason,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Cached is set for decisions served from the cache of
+	// client.CachingClient.
+	Cached bool `json:"cached,omitempty"`
+	// Fallback is the mode, e.g. fail_open, of a decision made by
+	// client.FallbackClient as the service couldn't be reached.
+	Fallback string `json:"fallback,omitempty"`
+	// Latency is the duration of the call, shared by all items of a bulk
+	// call.
+	Latency time.Duration `json:"latency"`
+	// Caller is metadata about the caller, as returned by the CallerFunc
+	// given to the client.
+	Caller map[string]string `json:"caller,omitempty"`
+}
+
+// Allowed reports whether the decision allowed the action.
+func (r Record) Allowed() bool {
+	return r.Decision == DecisionAllow
+}
+
+// Sink writes records.
+type Sink interface {
+	Write(record Record) error
+}
+
+// CallerFunc returns metadata about the caller of a call, e.g. the request ID
+// or the service acting on the decision.
+type CallerFunc func(ctx context.Context) map[string]string
+
+// Redacted returns a sink replacing the user ID of every record by
+// redact(userID) before writing it to sink.
+func Redacted(sink Sink, redact func(userID string) string) Sink {
+	return redactingSink{sink: sink, redact: redact}
+}
+
+type redactingSink struct {
+	sink   Sink
+	redact func(string) string
+}
+
+func (s redactingSink) Write(record Record) error {
+	record.UserID = s.redact(record.UserID)
+
+	return s.sink.Write(record)
+}
+
+// HashUserID returns a redaction replacing the user ID by its SHA-256 hash,
+// prefixed with salt, which still correlates the records of a user.
+func HashUserID(salt string) func(string) string {
+	return func(userID string) string {
+		hash := sha256.Sum256([]byte(salt + userID))
+
+		return hex.EncodeToString(hash[:])
+	}
+}
+
+// RemoveUserID is a redaction removing the user ID.
+func RemoveUserID(string) string {
+	return "redacted"
+}
@@ -0,0 +1,132 @@
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/audit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	records []audit.Record
+	err     error
+	block   chan struct{}
+}
+
+func (s *memorySink) Write(record audit.Record) error {
+	if s.block != nil {
+		<-s.block
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, record)
+
+	return s.err
+}
+
+func record(userID string, decision string) audit.Record {
+	return audit.Record{
+		Time:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Method:   "IsAuthorized",
+		UserID:   userID,
+		Action:   "read",
+		Resource: &audit.Resource{ID: "site", Type: "site"},
+		Decision: decision,
+		Latency:  time.Millisecond,
+		Caller:   map[string]string{"service": "test"},
+	}
+}
+
+func Test_JSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := audit.NewJSONSink(&buf)
+
+	require.NoError(t, sink.Write(record("a", audit.DecisionAllow)))
+	require.NoError(t, sink.Write(record("b", audit.DecisionDeny)))
+
+	var lines []audit.Record
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var r audit.Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		lines = append(lines, r)
+	}
+
+	require.Len(t, lines, 2)
+	assert.Equal(t, record("a", audit.DecisionAllow), lines[0])
+	assert.True(t, lines[0].Allowed())
+	assert.False(t, lines[1].Allowed())
+}
+
+func Test_ZapSink(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	require.NoError(t, audit.NewZapSink(zap.New(core)).Write(record("a", audit.DecisionDeny)))
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "a", fields["userId"])
+	assert.Equal(t, "deny", fields["decision"])
+	assert.Equal(t, "site", fields["resourceType"])
+}
+
+func Test_Redacted(t *testing.T) {
+	sink := &memorySink{}
+
+	require.NoError(t, audit.Redacted(sink, audit.HashUserID("salt")).Write(record("a", audit.DecisionAllow)))
+	require.NoError(t, audit.Redacted(sink, audit.HashUserID("salt")).Write(record("a", audit.DecisionAllow)))
+	require.NoError(t, audit.Redacted(sink, audit.RemoveUserID).Write(record("a", audit.DecisionAllow)))
+
+	require.Len(t, sink.records, 3)
+	assert.Len(t, sink.records[0].UserID, 64)
+	assert.Equal(t, sink.records[0].UserID, sink.records[1].UserID, "Hashes still correlate the records of a user")
+	assert.Equal(t, "redacted", sink.records[2].UserID)
+}
+
+func Test_AsyncSink(t *testing.T) {
+	sinkErr := errors.New("disk full")
+	next := &memorySink{err: sinkErr, block: make(chan struct{})}
+
+	var errs []error
+	sink := audit.NewAsyncSink(next, 2, func(err error) { errs = append(errs, err) })
+
+	for _, userID := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, sink.Write(record(userID, audit.DecisionAllow)))
+	}
+
+	close(next.block)
+	require.NoError(t, sink.Close())
+
+	// The first record may or may not have been taken off the buffer when
+	// the others were written.
+	assert.GreaterOrEqual(t, len(next.records), 2, "Buffered records are written on close")
+	assert.Equal(t, uint64(4-len(next.records)), sink.Dropped())
+	assert.Len(t, errs, len(next.records))
+	assert.ErrorIs(t, errs[0], sinkErr)
+}
+
+func Test_AsyncSink_WriteAfterClose(t *testing.T) {
+	next := &memorySink{}
+	sink := audit.NewAsyncSink(next, 1, nil)
+
+	require.NoError(t, sink.Close())
+	require.NoError(t, sink.Close(), "Close can be called again")
+
+	assert.ErrorIs(t, sink.Write(record("a", audit.DecisionAllow)), audit.ErrSinkClosed)
+	assert.Equal(t, uint64(1), sink.Dropped())
+	assert.Empty(t, next.records)
+}
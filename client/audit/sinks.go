@@ -0,0 +1,174 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// ZapSink logs every record as an info message.
+type ZapSink struct {
+	logger *zap.Logger
+}
+
+var _ Sink = &ZapSink{}
+
+func NewZapSink(logger *zap.Logger) *ZapSink {
+	return &ZapSink{logger: logger}
+}
+
+func (s *ZapSink) Write(record Record) error {
+	fields := []zap.Field{
+		zap.Time("time", record.Time),
+		zap.String("method", record.Method),
+		zap.String("userId", record.UserID),
+		zap.String("action", record.Action),
+		zap.String("decision", record.Decision),
+		zap.Duration("latency", record.Latency),
+	}
+
+	if record.Resource != nil {
+		fields = append(fields,
+			zap.String("resourceId", record.Resource.ID),
+			zap.String("resourceType", record.Resource.Type),
+			zap.String("resourceProvider", record.Resource.Provider),
+		)
+	}
+
+	if record.Endpoint != "" {
+		fields = append(fields, zap.String("endpoint", record.Endpoint))
+	}
+
+	if record.Reason != "" {
+		fields = append(fields, zap.String("reason", record.Reason))
+	}
+
+	if record.Error != "" {
+		fields = append(fields, zap.String("error", record.Error))
+	}
+
+	if record.Cached {
+		fields = append(fields, zap.Bool("cached", true))
+	}
+
+	if record.Fallback != "" {
+		fields = append(fields, zap.String("fallback", record.Fallback))
+	}
+
+	if len(record.Caller) > 0 {
+		fields = append(fields, zap.Any("caller", record.Caller))
+	}
+
+	s.logger.Info("authorization decision", fields...)
+
+	return nil
+}
+
+// JSONSink writes every record as a line of JSON.
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+var _ Sink = &JSONSink{}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONSink) Write(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enc.Encode(record)
+}
+
+// ErrSinkClosed is returned when writing to a closed AsyncSink.
+var ErrSinkClosed = errors.New("audit sink is closed")
+
+// AsyncSink writes the records to another sink from a goroutine, so that
+// calls aren't slowed down by the sink. Records written while the buffer is
+// full are dropped and counted.
+type AsyncSink struct {
+	sink    Sink
+	records chan Record
+	onError func(error)
+
+	dropped atomic.Uint64
+
+	// mu guards closed, so that no record is sent once records is closed.
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+var _ Sink = &AsyncSink{}
+
+// NewAsyncSink creates a sink buffering up to size records for sink. Errors
+// returned by sink are passed to onError, if not nil.
+func NewAsyncSink(sink Sink, size int, onError func(error)) *AsyncSink {
+	s := &AsyncSink{
+		sink:    sink,
+		records: make(chan Record, size),
+		onError: onError,
+		done:    make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Write buffers the record. Records written after Close are dropped and
+// ErrSinkClosed is returned.
+func (s *AsyncSink) Write(record Record) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		s.dropped.Add(1)
+		return ErrSinkClosed
+	}
+
+	select {
+	case s.records <- record:
+	default:
+		s.dropped.Add(1)
+	}
+
+	return nil
+}
+
+// Dropped returns the number of records dropped as the buffer was full or the
+// sink was closed.
+func (s *AsyncSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close writes the buffered records and stops the goroutine.
+func (s *AsyncSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.records)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+
+	return nil
+}
+
+func (s *AsyncSink) run() {
+	defer close(s.done)
+
+	for record := range s.records {
+		if err := s.sink.Write(record); err != nil && s.onError != nil {
+			s.onError(err)
+		}
+	}
+}
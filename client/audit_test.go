@@ -0,0 +1,167 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/client/audit"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	grpcapi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	records []audit.Record
+}
+
+func (s *recordingSink) Write(record audit.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, record)
+
+	return nil
+}
+
+func (s *recordingSink) take() []audit.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.records
+	s.records = nil
+
+	return records
+}
+
+type requestIDKey struct{}
+
+func Test_Audit(t *testing.T) {
+	server, err := authMock.NewStatefulServer()
+	require.NoError(t, err)
+	defer server.Stop(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	site := &common.Origin{Id: "site", Type: "site", Provider: "hierarchy"}
+	asset := &common.Origin{Id: "asset", Type: "asset", Provider: "hierarchy"}
+	require.NoError(t, server.Store.AddResources(ctx, []*common.Origin{site, asset}))
+	require.NoError(t, server.Store.AddAction(ctx, &grpcapi.Action{Name: "read"}))
+	require.NoError(t, server.Store.ApplyUserAction(ctx, "user", "read", site))
+
+	sink := &recordingSink{}
+	caller := func(ctx context.Context) map[string]string {
+		if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+			return map[string]string{"request_id": id}
+		}
+
+		return nil
+	}
+
+	c, err := authorize.New(ctx, net.JoinHostPort(server.HostPort()), authorize.WithInsecure(), authorize.WithAudit(sink, caller))
+	require.NoError(t, err)
+	defer c.Close()
+
+	t.Run("IsAuthorized", func(t *testing.T) {
+		ok, err := c.IsAuthorized(context.WithValue(ctx, requestIDKey{}, "1"), "user", "read", site)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		records := sink.take()
+		require.Len(t, records, 1)
+		assert.Equal(t, "IsAuthorized", records[0].Method)
+		assert.Equal(t, "user", records[0].UserID)
+		assert.Equal(t, "read", records[0].Action)
+		assert.Equal(t, &audit.Resource{ID: "site", Type: "site", Provider: "hierarchy"}, records[0].Resource)
+		assert.Equal(t, audit.DecisionAllow, records[0].Decision)
+		assert.Equal(t, map[string]string{"request_id": "1"}, records[0].Caller)
+		assert.False(t, records[0].Time.IsZero())
+	})
+
+	t.Run("IsAuthorizedWithReason", func(t *testing.T) {
+		ok, reason, err := c.IsAuthorizedWithReason(ctx, "user", "read", asset)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		records := sink.take()
+		require.Len(t, records, 1)
+		assert.Equal(t, "IsAuthorizedWithReason", records[0].Method)
+		assert.Equal(t, audit.DecisionDeny, records[0].Decision)
+		assert.Equal(t, reason, records[0].Reason)
+		assert.Nil(t, records[0].Caller)
+	})
+
+	t.Run("IsAuthorizedBulk", func(t *testing.T) {
+		_, _, err := c.IsAuthorizedBulk(ctx, "user", "read", []*common.Origin{site, asset})
+		require.NoError(t, err)
+
+		records := sink.take()
+		require.Len(t, records, 2, "Every item is recorded")
+
+		decisions := map[string]string{}
+		for _, record := range records {
+			assert.Equal(t, "IsAuthorizedBulk", record.Method)
+			decisions[record.Resource.ID] = record.Decision
+		}
+
+		assert.Equal(t, map[string]string{"site": audit.DecisionAllow, "asset": audit.DecisionDeny}, decisions)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := c.IsAuthorized(canceled, "user", "read", site)
+		require.Error(t, err)
+
+		records := sink.take()
+		require.Len(t, records, 1)
+		assert.Equal(t, audit.DecisionError, records[0].Decision)
+		assert.NotEmpty(t, records[0].Error)
+		assert.False(t, records[0].Allowed())
+	})
+
+	t.Run("other methods", func(t *testing.T) {
+		_, err := c.GetResource(ctx, "site", "site")
+		require.NoError(t, err)
+
+		assert.Empty(t, sink.take(), "Only decisions are recorded")
+	})
+}
+
+func Test_Audit_BulkFromOldServer(t *testing.T) {
+	server, err := authMock.NewServer()
+	require.NoError(t, err)
+
+	// Old servers set neither the resource nor its ID of the responses.
+	server.On("IsAuthorizedBulk", mock.Anything, mock.Anything).
+		Return(&grpcapi.IsAuthorizedBulkOutput{Responses: []*grpcapi.IsAuthorizedOutItem{{Ok: true}, {Ok: false}}}, nil)
+
+	sink := &recordingSink{}
+
+	c, err := authorize.New(context.Background(), net.JoinHostPort(server.HostPort()), authorize.WithInsecure(), authorize.WithAudit(sink, nil))
+	require.NoError(t, err)
+	defer c.Close()
+
+	site := &common.Origin{Id: "site", Type: "site", Provider: "hierarchy"}
+	asset := &common.Origin{Id: "asset", Type: "asset", Provider: "hierarchy"}
+
+	_, _, err = c.IsAuthorizedBulk(context.Background(), "user", "read", []*common.Origin{site, asset})
+	require.NoError(t, err)
+
+	records := sink.take()
+	require.Len(t, records, 2)
+	assert.Equal(t, &audit.Resource{ID: "site", Type: "site", Provider: "hierarchy"}, records[0].Resource, "The resources of the request are recorded by position")
+	assert.Equal(t, audit.DecisionAllow, records[0].Decision)
+	assert.Equal(t, &audit.Resource{ID: "asset", Type: "asset", Provider: "hierarchy"}, records[1].Resource)
+	assert.Equal(t, audit.DecisionDeny, records[1].Decision)
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SKF/proto/v2/common"
+)
+
+const (
+	DefaultBatchWindow       = 5 * time.Millisecond
+	DefaultBatchFlushTimeout = DefaultRequestTimeout
+)
+
+// BatchConfig configures how NewBatchingClient coalesces calls.
+type BatchConfig struct {
+	// Window is how long the first call of a batch waits for more calls to
+	// join it. Defaults to DefaultBatchWindow.
+	Window time.Duration
+	// MaxBatchSize is the number of distinct resources which causes a batch to
+	// be sent before the window has passed. Defaults to, and is capped at,
+	// REQUEST_LENGTH_LIMIT.
+	MaxBatchSize int
+	// FlushTimeout bounds the call sending a batch joined by a caller without
+	// a deadline. Defaults to DefaultBatchFlushTimeout.
+	FlushTimeout time.Duration
+}
+
+// BatchingClient wraps an AuthorizeClient and coalesces concurrent
+// IsAuthorized calls for the same user and action into a single
+// IsAuthorizedBulk call.
+type BatchingClient struct {
+	AuthorizeClient
+
+	config BatchConfig
+
+	mu      sync.Mutex
+	pending map[batchKey]*batch
+}
+
+var _ AuthorizeClient = &BatchingClient{}
+
+type batchKey struct {
+	userID, action string
+}
+
+type batch struct {
+	key batchKey
+	// ctx is the detached context of the call which opened the batch, the
+	// batch is not cancelled when that caller gives up.
+	ctx context.Context
+	// deadline is the latest deadline of the callers which joined the batch,
+	// bounding the call sending it.
+	deadline  time.Time
+	resources []*common.Origin
+	indices   map[originKey]int
+	timer     *time.Timer
+
+	done      chan struct{}
+	decisions map[originKey]bool
+	err       error
+}
+
+func NewBatchingClient(c AuthorizeClient, config BatchConfig) *BatchingClient {
+	if config.Window <= 0 {
+		config.Window = DefaultBatchWindow
+	}
+
+	if config.MaxBatchSize <= 0 || config.MaxBatchSize > REQUEST_LENGTH_LIMIT {
+		config.MaxBatchSize = REQUEST_LENGTH_LIMIT
+	}
+
+	if config.FlushTimeout <= 0 {
+		config.FlushTimeout = DefaultBatchFlushTimeout
+	}
+
+	return &BatchingClient{
+		AuthorizeClient: c,
+		config:          config,
+		pending:         make(map[batchKey]*batch),
+	}
+}
+
+func (c *BatchingClient) IsAuthorized(ctx context.Context, userID, action string, resource *common.Origin) (bool, error) {
+	b, key := c.join(ctx, batchKey{userID: userID, action: action}, resource)
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	if b.err != nil {
+		return false, b.err
+	}
+
+	ok, found := b.decisions[key]
+	if !found {
+		return false, fmt.Errorf("no decision returned for resource %q", resource.GetId())
+	}
+
+	return ok, nil
+}
+
+// join adds the resource to the pending batch for key, opening a new batch if
+// there is none, and sends the batch right away if it is full.
+func (c *BatchingClient) join(ctx context.Context, key batchKey, resource *common.Origin) (*batch, originKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.pending[key]
+	if !ok {
+		b = &batch{
+			key:     key,
+			ctx:     context.WithoutCancel(ctx),
+			indices: make(map[originKey]int),
+			done:    make(chan struct{}),
+		}
+		b.timer = time.AfterFunc(c.config.Window, func() { c.flush(b) })
+
+		c.pending[key] = b
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.config.FlushTimeout)
+	}
+
+	if deadline.After(b.deadline) {
+		b.deadline = deadline
+	}
+
+	resourceKey := keyOf(resource)
+	if _, ok := b.indices[resourceKey]; !ok {
+		b.indices[resourceKey] = len(b.resources)
+		b.resources = append(b.resources, resource)
+	}
+
+	if len(b.resources) >= c.config.MaxBatchSize {
+		delete(c.pending, key)
+
+		// If the timer has already fired the batch is sent by flush.
+		if b.timer.Stop() {
+			go c.send(b)
+		}
+	}
+
+	return b, resourceKey
+}
+
+func (c *BatchingClient) flush(b *batch) {
+	c.mu.Lock()
+	if c.pending[b.key] == b {
+		delete(c.pending, b.key)
+	}
+	c.mu.Unlock()
+
+	c.send(b)
+}
+
+func (c *BatchingClient) send(b *batch) {
+	defer close(b.done)
+
+	b.decisions = make(map[originKey]bool, len(b.resources))
+
+	ctx, cancel := context.WithDeadline(b.ctx, b.deadline)
+	defer cancel()
+
+	if len(b.resources) == 1 {
+		ok, err := c.AuthorizeClient.IsAuthorized(ctx, b.key.userID, b.key.action, b.resources[0])
+		b.decisions[keyOf(b.resources[0])] = ok
+		b.err = err
+
+		return
+	}
+
+	resources, oks, err := c.AuthorizeClient.IsAuthorizedBulk(ctx, b.key.userID, b.key.action, b.resources)
+	if err != nil {
+		b.err = err
+		return
+	}
+
+	for i := range oks {
+		var key originKey
+		if i < len(resources) {
+			key = keyOf(resources[i])
+		}
+
+		// Old servers only echo the resource id, fall back on the position
+		// of the response.
+		if _, known := b.indices[key]; !known && i < len(b.resources) {
+			key = keyOf(b.resources[i])
+		}
+
+		b.decisions[key] = oks[i]
+	}
+}
@@ -0,0 +1,113 @@
+package client_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// echoingBulkClient answers IsAuthorizedBulk by allowing the resources with
+// id 0 and 2 and records the size of every batch it receives.
+type echoingBulkClient struct {
+	*authMock.Client
+
+	mu      sync.Mutex
+	batches []int
+}
+
+func (c *echoingBulkClient) IsAuthorizedBulk(ctx context.Context, userID, action string, resources []*common.Origin) ([]*common.Origin, []bool, error) {
+	c.mu.Lock()
+	c.batches = append(c.batches, len(resources))
+	c.mu.Unlock()
+
+	oks := make([]bool, len(resources))
+	for i, resource := range resources {
+		oks[i] = resource.Id == "0" || resource.Id == "2"
+	}
+
+	return resources, oks, nil
+}
+
+func Test_BatchingClient_CoalescesConcurrentCalls(t *testing.T) {
+	nodes := generateNodes(4)
+
+	inner := &echoingBulkClient{Client: authMock.Create()}
+
+	client := authorize.NewBatchingClient(inner, authorize.BatchConfig{Window: time.Minute, MaxBatchSize: 2})
+
+	var wg sync.WaitGroup
+
+	oks := make([]bool, len(nodes))
+	for i := range nodes {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ok, err := client.IsAuthorized(context.Background(), "testUser", "testAction", nodes[i])
+			assert.NoError(t, err)
+
+			oks[i] = ok
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, []bool{true, false, true, false}, oks)
+	assert.Equal(t, []int{2, 2}, inner.batches)
+}
+
+func Test_BatchingClient_SingleCallAfterWindow(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "testAction", cachedNode).Return(true, nil).Once()
+
+	client := authorize.NewBatchingClient(inner, authorize.BatchConfig{Window: time.Millisecond})
+
+	ok, err := client.IsAuthorized(context.Background(), "testUser", "testAction", cachedNode)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	inner.AssertExpectations(t)
+}
+
+func Test_BatchingClient_CallerCancellation(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "testAction", cachedNode).Return(true, nil).Maybe()
+
+	client := authorize.NewBatchingClient(inner, authorize.BatchConfig{Window: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	_, err := client.IsAuthorized(ctx, "testUser", "testAction", cachedNode)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_BatchingClient_BoundsFlush(t *testing.T) {
+	node := generateNodes(1)[0]
+
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		deadline, ok := ctx.Deadline()
+		return ok && time.Until(deadline) <= time.Minute
+	})
+
+	inner := authMock.Create()
+	inner.On("IsAuthorized", hasDeadline, "testUser", "testAction", node).Return(true, nil).Once()
+
+	client := authorize.NewBatchingClient(inner, authorize.BatchConfig{Window: time.Millisecond, FlushTimeout: time.Minute})
+
+	ok, err := client.IsAuthorized(context.Background(), "testUser", "testAction", node)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	inner.AssertExpectations(t)
+}
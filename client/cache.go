@@ -0,0 +1,235 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/audit"
+	"github.com/SKF/go-utility/v2/log"
+	"github.com/SKF/proto/v2/common"
+)
+
+const DefaultCacheMaxEntries = 10000
+
+// CacheConfig configures the decision cache used by NewCachingClient.
+type CacheConfig struct {
+	// TTL is how long a positive decision is cached. Zero disables caching of
+	// positive decisions.
+	TTL time.Duration
+	// NegativeTTL is how long a negative decision is cached. Zero disables
+	// caching of negative decisions.
+	NegativeTTL time.Duration
+	// MaxEntries bounds the number of cached decisions, the least recently
+	// used decision is evicted first. Defaults to DefaultCacheMaxEntries.
+	MaxEntries int
+	// Audit, if not nil, receives a record of every decision served from the
+	// cache, with audit.Record.Cached set. The decisions fetched from the
+	// service are recorded by the client given WithAudit, if any.
+	Audit audit.Sink
+	// AuditCaller returns the caller metadata of the audit records.
+	AuditCaller audit.CallerFunc
+	// AuditLogger logs the errors returned by Audit. Defaults to log.Base().
+	AuditLogger log.Logger
+}
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+type decision struct {
+	ok        bool
+	reason    string
+	expiresAt time.Time
+}
+
+// CachingClient wraps an AuthorizeClient and caches the decisions returned by
+// IsAuthorized, IsAuthorizedWithReason and IsAuthorizedByEndpoint. Writes made
+// through the same CachingClient invalidate the affected decisions, writes made
+// through other clients are only picked up once the cached decisions expire.
+type CachingClient struct {
+	invalidatingClient[decision]
+
+	config CacheConfig
+
+	mu    sync.Mutex
+	stats CacheStats
+}
+
+var _ AuthorizeClient = &CachingClient{}
+
+func NewCachingClient(c AuthorizeClient, config CacheConfig) *CachingClient {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultCacheMaxEntries
+	}
+
+	return &CachingClient{
+		invalidatingClient: invalidatingClient[decision]{
+			AuthorizeClient: c,
+			decisions:       newDecisionStore[decision](config.MaxEntries),
+		},
+		config: config,
+	}
+}
+
+func (c *CachingClient) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.decisions.len()
+
+	return stats
+}
+
+// Purge drops every cached decision.
+func (c *CachingClient) Purge() {
+	c.decisions.purge()
+}
+
+func (c *CachingClient) IsAuthorized(ctx context.Context, userID, action string, resource *common.Origin) (bool, error) {
+	key := decisionKey{
+		kind:     decisionIsAuthorized,
+		userID:   userID,
+		action:   action,
+		resource: keyOf(resource),
+	}
+
+	d, generation, cached := c.lookup(key)
+	if cached {
+		c.audit(ctx, audit.Record{
+			Method:   "IsAuthorized",
+			UserID:   userID,
+			Action:   action,
+			Resource: auditResource(resource),
+		}, d)
+
+		return d.ok, nil
+	}
+
+	ok, err := c.AuthorizeClient.IsAuthorized(ctx, userID, action, resource)
+	if err != nil {
+		return false, err
+	}
+
+	c.store(key, generation, decision{ok: ok})
+
+	return ok, nil
+}
+
+func (c *CachingClient) IsAuthorizedWithReason(ctx context.Context, userID, action string, resource *common.Origin) (bool, string, error) {
+	key := decisionKey{
+		kind:     decisionWithReason,
+		userID:   userID,
+		action:   action,
+		resource: keyOf(resource),
+	}
+
+	d, generation, cached := c.lookup(key)
+	if cached {
+		c.audit(ctx, audit.Record{
+			Method:   "IsAuthorizedWithReason",
+			UserID:   userID,
+			Action:   action,
+			Resource: auditResource(resource),
+		}, d)
+
+		return d.ok, d.reason, nil
+	}
+
+	ok, reason, err := c.AuthorizeClient.IsAuthorizedWithReason(ctx, userID, action, resource)
+	if err != nil {
+		return ok, reason, err
+	}
+
+	c.store(key, generation, decision{ok: ok, reason: reason})
+
+	return ok, reason, nil
+}
+
+func (c *CachingClient) IsAuthorizedByEndpoint(ctx context.Context, api, method, endpoint, userID string) (bool, error) {
+	key := decisionKey{
+		kind:     decisionByEndpoint,
+		userID:   userID,
+		api:      api,
+		method:   method,
+		endpoint: endpoint,
+	}
+
+	d, generation, cached := c.lookup(key)
+	if cached {
+		c.audit(ctx, audit.Record{
+			Method:   "IsAuthorizedByEndpoint",
+			UserID:   userID,
+			Endpoint: strings.Join([]string{api, method, endpoint}, " "),
+		}, d)
+
+		return d.ok, nil
+	}
+
+	ok, err := c.AuthorizeClient.IsAuthorizedByEndpoint(ctx, api, method, endpoint, userID)
+	if err != nil {
+		return false, err
+	}
+
+	c.store(key, generation, decision{ok: ok})
+
+	return ok, nil
+}
+
+func (c *CachingClient) audit(ctx context.Context, record audit.Record, d decision) {
+	if c.config.Audit == nil {
+		return
+	}
+
+	record.Decision = auditDecision(d.ok)
+	record.Reason = d.reason
+	record.Cached = true
+
+	writeAudit(ctx, c.config.AuditLogger, c.config.Audit, c.config.AuditCaller, record)
+}
+
+// lookup returns the cached decision for key if there is a fresh one, and
+// the generation which must be passed on to store.
+func (c *CachingClient) lookup(key decisionKey) (decision, uint64, bool) {
+	now := time.Now()
+	d, generation, ok := c.decisions.lookup(key, func(d decision) bool {
+		return now.Before(d.expiresAt)
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ok {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+
+	return d, generation, ok
+}
+
+// store caches the decision unless the cache was invalidated while the
+// decision was being fetched, in which case it might already be stale.
+func (c *CachingClient) store(key decisionKey, generation uint64, d decision) {
+	ttl := c.config.TTL
+	if !d.ok {
+		ttl = c.config.NegativeTTL
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	d.expiresAt = time.Now().Add(ttl)
+
+	if c.decisions.add(key, generation, d) {
+		c.mu.Lock()
+		c.stats.Evictions++
+		c.mu.Unlock()
+	}
+}
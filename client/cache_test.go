@@ -0,0 +1,138 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/client/audit"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var cachedNode = &common.Origin{Id: "0", Type: "node", Provider: "1"}
+
+func Test_CachingClient_CachesDecisions(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "testAction", cachedNode).Return(true, nil).Once()
+
+	client := authorize.NewCachingClient(inner, authorize.CacheConfig{TTL: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		ok, err := client.IsAuthorized(context.Background(), "testUser", "testAction", cachedNode)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+
+	stats := client.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 1, stats.Entries)
+
+	inner.AssertExpectations(t)
+}
+
+func Test_CachingClient_NegativeTTL(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "testAction", cachedNode).Return(false, nil).Twice()
+
+	client := authorize.NewCachingClient(inner, authorize.CacheConfig{TTL: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		ok, err := client.IsAuthorized(context.Background(), "testUser", "testAction", cachedNode)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+
+	assert.Equal(t, 0, client.Stats().Entries, "Negative decisions are not cached without a NegativeTTL")
+
+	inner.AssertExpectations(t)
+}
+
+func Test_CachingClient_Expiry(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorizedWithReason", mock.Anything, "testUser", "testAction", cachedNode).Return(false, authorize.ReasonAccessDenied, nil).Twice()
+
+	client := authorize.NewCachingClient(inner, authorize.CacheConfig{NegativeTTL: 10 * time.Millisecond})
+
+	_, reason, err := client.IsAuthorizedWithReason(context.Background(), "testUser", "testAction", cachedNode)
+	require.NoError(t, err)
+	assert.Equal(t, authorize.ReasonAccessDenied, reason)
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, reason, err = client.IsAuthorizedWithReason(context.Background(), "testUser", "testAction", cachedNode)
+	require.NoError(t, err)
+	assert.Equal(t, authorize.ReasonAccessDenied, reason)
+
+	inner.AssertExpectations(t)
+}
+
+func Test_CachingClient_EvictsLeastRecentlyUsed(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorizedByEndpoint", mock.Anything, "api", "GET", "/a", "testUser").Return(true, nil).Once()
+	inner.On("IsAuthorizedByEndpoint", mock.Anything, "api", "GET", "/b", "testUser").Return(true, nil).Once()
+
+	client := authorize.NewCachingClient(inner, authorize.CacheConfig{TTL: time.Minute, MaxEntries: 1})
+
+	_, err := client.IsAuthorizedByEndpoint(context.Background(), "api", "GET", "/a", "testUser")
+	require.NoError(t, err)
+	_, err = client.IsAuthorizedByEndpoint(context.Background(), "api", "GET", "/b", "testUser")
+	require.NoError(t, err)
+
+	stats := client.Stats()
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, 1, stats.Entries)
+
+	inner.AssertExpectations(t)
+}
+
+func Test_CachingClient_InvalidatesOnWrites(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "testAction", cachedNode).Return(false, nil).Once()
+	inner.On("ApplyUserAction", mock.Anything, "testUser", "testAction", cachedNode).Return(nil).Once()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "testAction", cachedNode).Return(true, nil).Once()
+	inner.On("RemoveResource", mock.Anything, cachedNode).Return(nil).Once()
+
+	client := authorize.NewCachingClient(inner, authorize.CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute})
+
+	ok, err := client.IsAuthorized(context.Background(), "testUser", "testAction", cachedNode)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, client.ApplyUserAction(context.Background(), "testUser", "testAction", cachedNode))
+
+	ok, err = client.IsAuthorized(context.Background(), "testUser", "testAction", cachedNode)
+	require.NoError(t, err)
+	assert.True(t, ok, "Applying an action invalidates the decisions cached for the user")
+
+	require.NoError(t, client.RemoveResource(context.Background(), cachedNode))
+	assert.Equal(t, 0, client.Stats().Entries)
+
+	inner.AssertExpectations(t)
+}
+
+func Test_CachingClient_Audit(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "testAction", cachedNode).Return(true, nil).Once()
+
+	sink := &recordingSink{}
+	client := authorize.NewCachingClient(inner, authorize.CacheConfig{TTL: time.Minute, Audit: sink})
+
+	for i := 0; i < 2; i++ {
+		_, err := client.IsAuthorized(context.Background(), "testUser", "testAction", cachedNode)
+		require.NoError(t, err)
+	}
+
+	records := sink.take()
+	require.Len(t, records, 1, "Only the decision served from the cache is recorded")
+	assert.True(t, records[0].Cached)
+	assert.Equal(t, audit.DecisionAllow, records[0].Decision)
+	assert.Equal(t, "testUser", records[0].UserID)
+	assert.Equal(t, "0", records[0].Resource.ID)
+}
@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	authorizeApi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+)
+
+const DefaultChunkConcurrency = 4
+
+// ChunkConfig configures how NewChunkingClient splits bulk calls.
+type ChunkConfig struct {
+	// ChunkSize is the maximum number of items sent in a single call.
+	// Defaults to, and is capped at, REQUEST_LENGTH_LIMIT.
+	ChunkSize int
+	// Concurrency is the maximum number of chunks in flight at once.
+	// Defaults to DefaultChunkConcurrency.
+	Concurrency int
+}
+
+// ChunkingClient wraps an AuthorizeClient and splits bulk calls exceeding the
+// request length limit into chunks which are sent with bounded concurrency.
+// The chunks are not sent atomically, when a ChunkError is returned from a
+// write the chunks which are not listed in it have been applied.
+type ChunkingClient struct {
+	AuthorizeClient
+
+	config ChunkConfig
+}
+
+var _ AuthorizeClient = &ChunkingClient{}
+
+func NewChunkingClient(c AuthorizeClient, config ChunkConfig) *ChunkingClient {
+	if config.ChunkSize <= 0 || config.ChunkSize > REQUEST_LENGTH_LIMIT {
+		config.ChunkSize = REQUEST_LENGTH_LIMIT
+	}
+
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultChunkConcurrency
+	}
+
+	return &ChunkingClient{
+		AuthorizeClient: c,
+		config:          config,
+	}
+}
+
+// ChunkFailure describes a chunk, items [Offset, Offset+Length) of the input,
+// which could not be processed.
+type ChunkFailure struct {
+	Chunk  int
+	Offset int
+	Length int
+	Err    error
+}
+
+// ChunkError is returned when one or more chunks of a chunked call failed.
+type ChunkError struct {
+	Method   string
+	Chunks   int
+	Failures []ChunkFailure
+}
+
+func (e *ChunkError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		failures[i] = fmt.Sprintf("chunk %d [%d:%d]: %s", failure.Chunk, failure.Offset, failure.Offset+failure.Length, failure.Err)
+	}
+
+	return fmt.Sprintf("%s: %d of %d chunks failed: %s", e.Method, len(e.Failures), e.Chunks, strings.Join(failures, "; "))
+}
+
+func (e *ChunkError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure.Err
+	}
+
+	return errs
+}
+
+func (c *ChunkingClient) AddResources(ctx context.Context, resources []*common.Origin) error {
+	return c.forEachChunk(ctx, "AddResources", len(resources), func(ctx context.Context, start, end int) error {
+		return c.AuthorizeClient.AddResources(ctx, resources[start:end])
+	})
+}
+
+func (c *ChunkingClient) RemoveResources(ctx context.Context, resources []*common.Origin) error {
+	return c.forEachChunk(ctx, "RemoveResources", len(resources), func(ctx context.Context, start, end int) error {
+		return c.AuthorizeClient.RemoveResources(ctx, resources[start:end])
+	})
+}
+
+func (c *ChunkingClient) AddResourceRelations(ctx context.Context, resources *authorizeApi.AddResourceRelationsInput) error {
+	relations := resources.GetRelation()
+
+	return c.forEachChunk(ctx, "AddResourceRelations", len(relations), func(ctx context.Context, start, end int) error {
+		return c.AuthorizeClient.AddResourceRelations(ctx, &authorizeApi.AddResourceRelationsInput{
+			Relation: relations[start:end],
+		})
+	})
+}
+
+func (c *ChunkingClient) RemoveResourceRelations(ctx context.Context, resources *authorizeApi.RemoveResourceRelationsInput) error {
+	relations := resources.GetRelation()
+
+	return c.forEachChunk(ctx, "RemoveResourceRelations", len(relations), func(ctx context.Context, start, end int) error {
+		return c.AuthorizeClient.RemoveResourceRelations(ctx, &authorizeApi.RemoveResourceRelationsInput{
+			Relation: relations[start:end],
+		})
+	})
+}
+
+func (c *ChunkingClient) IsAuthorizedBulk(ctx context.Context, userID, action string, resourcesInput []*common.Origin) ([]*common.Origin, []bool, error) {
+	// A single chunk is returned as the service answered it, the responses
+	// only need to line up with the resources when chunks are merged.
+	if len(resourcesInput) <= c.config.ChunkSize {
+		return c.AuthorizeClient.IsAuthorizedBulk(ctx, userID, action, resourcesInput)
+	}
+
+	resources := make([]*common.Origin, len(resourcesInput))
+	oks := make([]bool, len(resourcesInput))
+
+	err := c.forEachChunk(ctx, "IsAuthorizedBulk", len(resourcesInput), func(ctx context.Context, start, end int) error {
+		chunkResources, chunkOks, err := c.AuthorizeClient.IsAuthorizedBulk(ctx, userID, action, resourcesInput[start:end])
+		if err != nil {
+			return err
+		}
+
+		if len(chunkResources) != end-start || len(chunkOks) != end-start {
+			return fmt.Errorf("expected %d responses, got %d", end-start, len(chunkOks))
+		}
+
+		copy(resources[start:end], chunkResources)
+		copy(oks[start:end], chunkOks)
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resources, oks, nil
+}
+
+// forEachChunk calls fn for every chunk of the n items and collects the
+// failures into a ChunkError. Chunks which have not been started when ctx is
+// done are reported as failed with the context error.
+func (c *ChunkingClient) forEachChunk(ctx context.Context, method string, n int, fn func(ctx context.Context, start, end int) error) error {
+	chunks := (n + c.config.ChunkSize - 1) / c.config.ChunkSize
+	if chunks <= 1 {
+		return fn(ctx, 0, n)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		failures  []ChunkFailure
+		semaphore = make(chan struct{}, c.config.Concurrency)
+	)
+
+	fail := func(failure ChunkFailure) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		failures = append(failures, failure)
+	}
+
+	for chunk := 0; chunk < chunks; chunk++ {
+		start := chunk * c.config.ChunkSize
+		end := min(start+c.config.ChunkSize, n)
+		failure := ChunkFailure{Chunk: chunk, Offset: start, Length: end - start}
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			failure.Err = ctx.Err()
+			fail(failure)
+
+			continue
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if err := fn(ctx, start, end); err != nil {
+				failure.Err = err
+				fail(failure)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(failures, func(a, b ChunkFailure) int {
+		return a.Chunk - b.Chunk
+	})
+
+	return &ChunkError{
+		Method:   method,
+		Chunks:   chunks,
+		Failures: failures,
+	}
+}
@@ -0,0 +1,108 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func generateNodes(n int) []*common.Origin {
+	nodes := make([]*common.Origin, n)
+	for i := range nodes {
+		nodes[i] = &common.Origin{Id: fmt.Sprint(i), Type: "node", Provider: "1"}
+	}
+
+	return nodes
+}
+
+func Test_ChunkingClient_AddResources(t *testing.T) {
+	nodes := generateNodes(2500)
+
+	inner := authMock.Create()
+	inner.On("AddResources", mock.Anything, nodes[0:1000]).Return(nil).Once()
+	inner.On("AddResources", mock.Anything, nodes[1000:2000]).Return(nil).Once()
+	inner.On("AddResources", mock.Anything, nodes[2000:2500]).Return(nil).Once()
+
+	client := authorize.NewChunkingClient(inner, authorize.ChunkConfig{})
+
+	require.NoError(t, client.AddResources(context.Background(), nodes))
+
+	inner.AssertExpectations(t)
+}
+
+func Test_ChunkingClient_AggregatesFailures(t *testing.T) {
+	nodes := generateNodes(25)
+	errFailed := errors.New("failed")
+
+	inner := authMock.Create()
+	inner.On("RemoveResources", mock.Anything, nodes[0:10]).Return(nil).Once()
+	inner.On("RemoveResources", mock.Anything, nodes[10:20]).Return(errFailed).Once()
+	inner.On("RemoveResources", mock.Anything, nodes[20:25]).Return(nil).Once()
+
+	client := authorize.NewChunkingClient(inner, authorize.ChunkConfig{ChunkSize: 10})
+
+	err := client.RemoveResources(context.Background(), nodes)
+	require.ErrorIs(t, err, errFailed)
+
+	var chunkErr *authorize.ChunkError
+	require.ErrorAs(t, err, &chunkErr)
+	assert.Equal(t, 3, chunkErr.Chunks)
+	assert.Equal(t, []authorize.ChunkFailure{{Chunk: 1, Offset: 10, Length: 10, Err: errFailed}}, chunkErr.Failures)
+
+	inner.AssertExpectations(t)
+}
+
+func Test_ChunkingClient_IsAuthorizedBulkKeepsInputOrder(t *testing.T) {
+	nodes := generateNodes(25)
+
+	inner := authMock.Create()
+	for start := 0; start < len(nodes); start += 10 {
+		end := min(start+10, len(nodes))
+
+		oks := make([]bool, end-start)
+		for i := range oks {
+			oks[i] = (start+i)%2 == 0
+		}
+
+		inner.On("IsAuthorizedBulk", "testUser", "testAction", nodes[start:end]).Return(nodes[start:end], oks, nil).Once()
+	}
+
+	client := authorize.NewChunkingClient(inner, authorize.ChunkConfig{ChunkSize: 10, Concurrency: 3})
+
+	resources, oks, err := client.IsAuthorizedBulk(context.Background(), "testUser", "testAction", nodes)
+	require.NoError(t, err)
+	require.Len(t, oks, len(nodes))
+
+	for i := range nodes {
+		assert.Equal(t, nodes[i], resources[i])
+		assert.Equal(t, i%2 == 0, oks[i])
+	}
+
+	inner.AssertExpectations(t)
+}
+
+func Test_ChunkingClient_IsAuthorizedBulkSingleChunk(t *testing.T) {
+	nodes := generateNodes(3)
+
+	// Old servers only answer for some of the resources.
+	inner := authMock.Create()
+	inner.On("IsAuthorizedBulk", "testUser", "testAction", nodes).Return(nodes[:1], []bool{true}, nil).Once()
+
+	client := authorize.NewChunkingClient(inner, authorize.ChunkConfig{ChunkSize: 10})
+
+	resources, oks, err := client.IsAuthorizedBulk(context.Background(), "testUser", "testAction", nodes)
+	require.NoError(t, err, "A single chunk is returned as the service answered it")
+	assert.Equal(t, nodes[:1], resources)
+	assert.Equal(t, []bool{true}, oks)
+
+	inner.AssertExpectations(t)
+}
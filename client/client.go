@@ -2,23 +2,24 @@ package client
 
 import (
 	"context"
-	_ "embed"
+	"errors"
+	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
 	authorizeApi "github.com/SKF/proto/v2/authorize"
 	"github.com/SKF/proto/v2/common"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/resolver"
 )
 
-//go:embed service_config.json
-var defaultServiceConfig string
-
 type client struct {
-	conn           *grpc.ClientConn
-	api            authorizeApi.AuthorizeClient
-	requestTimeout time.Duration
+	conn    *grpc.ClientConn
+	api     authorizeApi.AuthorizeClient
+	closers []io.Closer
+	// requestTimeout is the time.Duration given to calls made without a
+	// deadline, it is changed by SetRequestTimeout while calls are made.
+	requestTimeout atomic.Int64
 }
 
 type AuthorizeClient interface {
@@ -71,18 +72,23 @@ type AuthorizeClient interface {
 	RemoveUserRole(ctx context.Context, roleName string) error
 }
 
+// DefaultRequestTimeout is the deadline given to calls made without one.
+const DefaultRequestTimeout = 60 * time.Second
+
 func CreateClient() AuthorizeClient {
-	return &client{
-		requestTimeout: 60 * time.Second,
-	}
+	c := &client{}
+	c.requestTimeout.Store(int64(DefaultRequestTimeout))
+
+	return c
 }
 
-// Dial creates a client connection to the given host with context (for timeout and transaction id)
+// Dial creates a client connection to the given host with context (for timeout and transaction id).
+// The host is resolved using DNS unless it is prefixed with a scheme, e.g. passthrough:///host.
 func (c *client) Dial(ctx context.Context, host, port string, opts ...grpc.DialOption) error {
-	resolver.SetDefaultScheme("dns")
-	opts = append(opts, grpc.WithDefaultServiceConfig(defaultServiceConfig))
+	opts = append([]grpc.DialOption{grpc.WithDefaultServiceConfig(defaultServiceConfig)}, opts...)
+	opts = append(opts, grpc.WithChainUnaryInterceptor(c.requestTimeoutInterceptor))
 
-	conn, err := grpc.NewClient(host+":"+port, opts...)
+	conn, err := grpc.NewClient(dialTarget(host, port), opts...)
 	if err != nil {
 		return err
 	}
@@ -95,17 +101,17 @@ func (c *client) Dial(ctx context.Context, host, port string, opts ...grpc.DialO
 
 // DialUsingCredentials creates a client connection to the given host with context (for timeout and transaction id)
 func (c *client) DialUsingCredentialsManager(ctx context.Context, cf credentialsmanager.CredentialsFetcher, host, port, secretKey string, opts ...grpc.DialOption) error {
-	resolver.SetDefaultScheme("dns")
 	opts = append([]grpc.DialOption{grpc.WithDefaultServiceConfig(defaultServiceConfig)}, opts...)
+	target := dialTarget(host, port)
 
-	opt, err := getCredentialOption(ctx, cf, host, secretKey)
+	opt, err := getCredentialOption(ctx, cf, hostOf(target), secretKey)
 	if err != nil {
 		return err
 	}
 
-	newOpts := append(opts, opt, withDefaultRequestTimeout(c.requestTimeout))
+	newOpts := append(opts, opt, grpc.WithChainUnaryInterceptor(c.requestTimeoutInterceptor))
 
-	conn, err := grpc.NewClient(host+":"+port, newOpts...)
+	conn, err := grpc.NewClient(target, newOpts...)
 	if err != nil {
 		return err
 	}
@@ -117,27 +123,32 @@ func (c *client) DialUsingCredentialsManager(ctx context.Context, cf credentials
 }
 
 func (c *client) Close() error {
-	if c.conn == nil {
-		return nil
+	var errs []error
+	for _, closer := range c.closers {
+		errs = append(errs, closer.Close())
+	}
+
+	if c.conn != nil {
+		errs = append(errs, c.conn.Close())
 	}
 
-	return c.conn.Close()
+	return errors.Join(errs...)
 }
 
 func (c *client) DeepPing(ctx context.Context) error {
 	_, err := c.api.DeepPing(ctx, &common.Void{})
-	return err
+	return wrapError(err, "DeepPing", requestIDs{})
 }
 
-func withDefaultRequestTimeout(requestTimeout time.Duration) grpc.DialOption {
-	return grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		var cancel context.CancelFunc
+// requestTimeoutInterceptor gives calls made without a deadline the request
+// timeout of the client.
+func (c *client) requestTimeoutInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	var cancel context.CancelFunc
 
-		if _, ok := ctx.Deadline(); !ok {
-			ctx, cancel = context.WithTimeout(ctx, requestTimeout)
-			defer cancel()
-		}
+	if _, ok := ctx.Deadline(); !ok {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(c.requestTimeout.Load()))
+		defer cancel()
+	}
 
-		return invoker(ctx, method, req, reply, cc, opts...)
-	})
+	return invoker(ctx, method, req, reply, cc, opts...)
 }
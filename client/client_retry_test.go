@@ -1,23 +1,15 @@
 package client_test
 
 import (
-	"bytes"
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/tls"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	_ "embed"
-	"encoding/pem"
-	"fmt"
-	"math/big"
 	"net"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/SKF/go-enlight-authorizer/client"
 	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	"github.com/SKF/go-enlight-authorizer/pkitest"
 
 	authorizeproto "github.com/SKF/proto/v2/authorize"
 	"github.com/SKF/proto/v2/common"
@@ -28,30 +20,38 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-var (
-	//go:embed certs/rsa-key.pem
-	rsaKey []byte
-)
-
 const DistantFuture = 10 * 365 * 24 * time.Hour
 
-func setup(t *testing.T, authorizeServer dummyAuthorizeServer) (credentialsmanager.DataStore, server) {
-	privateKey, err := parseRSAKey()
+// testCA issues the certificates of all tests, which therefore trust each
+// other.
+var testCA = sync.OnceValues(func() (*pkitest.CA, error) {
+	return pkitest.NewCA()
+})
+
+func issue(t *testing.T, opts ...pkitest.Option) credentialsmanager.DataStore {
+	ca, err := testCA()
 	require.NoError(t, err)
 
-	caCertPEM, err := generateCA(privateKey)
+	ds, err := ca.Issue(opts...)
 	require.NoError(t, err)
 
-	serverDataStore, err := generateDatastore(ca, privateKey, caCertPEM, DistantFuture)
+	return ds
+}
+
+func setup(t *testing.T, authorizeServer dummyAuthorizeServer) (credentialsmanager.DataStore, server) {
+	ca, err := testCA()
 	require.NoError(t, err)
 
-	clientDataStore, err := generateDatastore(ca, privateKey, caCertPEM, DistantFuture)
+	serverDataStore, err := ca.Server(pkitest.WithValidFor(DistantFuture))
 	require.NoError(t, err)
 
-	tlsCredentials, err := loadTLSCredentials(serverDataStore)
+	clientDataStore, err := ca.Client(pkitest.WithValidFor(DistantFuture))
 	require.NoError(t, err)
 
-	srv := newServer(tlsCredentials, authorizeServer)
+	config, err := pkitest.ServerTLSConfig(serverDataStore)
+	require.NoError(t, err)
+
+	srv := newServer(credentials.NewTLS(config), authorizeServer)
 	err = srv.Start()
 	require.NoError(t, err)
 
@@ -66,7 +66,7 @@ func TestDeadline(t *testing.T) {
 
 	childCtx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
-	err := c.DialUsingCredentialsManager(childCtx, &mockCredentialsFetcher{ds: clientDataStore}, "localhost", "10000", "")
+	err := c.DialUsingCredentialsManager(childCtx, pkitest.NewFetcher(clientDataStore), "localhost", "10000", "")
 	require.NoError(t, err)
 
 	srv.Shutdown()
@@ -77,8 +77,9 @@ func TestDeadline(t *testing.T) {
 
 	_, err = c.GetResource(context.Background(), "", "")
 
-	require.EqualError(t, err, "rpc error: code = DeadlineExceeded desc = context deadline exceeded",
+	require.EqualError(t, err, "GetResource: rpc error: code = DeadlineExceeded desc = context deadline exceeded",
 		"Caller omits deadline and the default request timeout is used")
+	require.ErrorIs(t, err, client.ErrDeadlineExceeded)
 }
 
 func TestReconnect(t *testing.T) {
@@ -86,7 +87,7 @@ func TestReconnect(t *testing.T) {
 
 	c := client.CreateClient()
 
-	err := c.DialUsingCredentialsManager(context.Background(), &mockCredentialsFetcher{ds: clientDataStore}, "localhost", "10000", "")
+	err := c.DialUsingCredentialsManager(context.Background(), pkitest.NewFetcher(clientDataStore), "localhost", "10000", "")
 	require.NoError(t, err)
 
 	err = srv.Restart()
@@ -106,7 +107,7 @@ func TestRetryPolicy(t *testing.T) {
 
 	c := client.CreateClient()
 
-	err := c.DialUsingCredentialsManager(context.Background(), &mockCredentialsFetcher{ds: clientDataStore}, "localhost", "10000", "")
+	err := c.DialUsingCredentialsManager(context.Background(), pkitest.NewFetcher(clientDataStore), "localhost", "10000", "")
 	require.NoError(t, err)
 
 	_, err = c.GetResource(context.Background(), "", "")
@@ -114,22 +115,14 @@ func TestRetryPolicy(t *testing.T) {
 }
 
 func TestClientHandshake_CertificateAboutToExpire(t *testing.T) {
-	privateKey, err := parseRSAKey()
-	require.NoError(t, err)
-
-	caCertPEM, err := generateCA(privateKey)
-	require.NoError(t, err)
-
-	ds, err := generateDatastore(ca, privateKey, caCertPEM, client.CertificateGracePeriod-time.Second)
-	require.NoError(t, err)
-
-	cf := &mockCredentialsFetcher{ds: ds}
+	// The fetcher is not a Watcher, so only the grace period triggers a reload.
+	cf := &rotatingFetcher{ds: datastoreValidFor(t, client.CertificateGracePeriod-time.Second)}
 
 	ctx := context.Background()
 	tls, err := client.NewAutoRefreshingTransportCredentials(ctx, cf, "secret", "localhost")
 	require.NoError(t, err)
 
-	require.Equal(t, 1, cf.callCount, "Certificates are loaded once during initialization")
+	require.Equal(t, 1, cf.calls, "Certificates are loaded once during initialization")
 
 	server, client := net.Pipe()
 	// Close pipe to avoid client getting stuck in an infinite reconnect loop
@@ -137,116 +130,17 @@ func TestClientHandshake_CertificateAboutToExpire(t *testing.T) {
 	require.NoError(t, err)
 
 	// Swap out certificates for fresh ones
-	cf.ds, err = generateDatastore(ca, privateKey, caCertPEM, DistantFuture)
-	require.NoError(t, err)
+	cf.set(datastoreValidFor(t, DistantFuture), nil)
 
 	_, _, err = tls.ClientHandshake(ctx, "", client)
 	require.Error(t, err, "io: read/write on closed pipe")
 
-	require.Equal(t, 2, cf.callCount, "Certificates are about to expiry and should be reloaded")
+	require.Equal(t, 2, cf.calls, "Certificates are about to expiry and should be reloaded")
 
 	_, _, err = tls.ClientHandshake(ctx, "", client)
 	require.Error(t, err, "io: read/write on closed pipe")
 
-	require.Equal(t, 2, cf.callCount, "Cached certificates are still valid and should not be reloaded")
-}
-
-var ca = &x509.Certificate{
-	SerialNumber:          big.NewInt(2019),
-	Subject:               pkix.Name{},
-	NotBefore:             time.Now(),
-	NotAfter:              time.Now().Add(DistantFuture),
-	IsCA:                  true,
-	BasicConstraintsValid: true,
-}
-
-func generateCA(privateKey *rsa.PrivateKey) ([]byte, error) {
-	caBytes, err := x509.CreateCertificate(rand.Reader, ca, ca, &privateKey.PublicKey, privateKey)
-	if err != nil {
-		return nil, err
-	}
-
-	caPEM := new(bytes.Buffer)
-	err = pem.Encode(caPEM, &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: caBytes,
-	})
-
-	return caPEM.Bytes(), err
-}
-
-func generateDatastore(ca *x509.Certificate, privateKey *rsa.PrivateKey, caCertPEM []byte, validTime time.Duration) (credentialsmanager.DataStore, error) {
-	ds := credentialsmanager.DataStore{}
-
-	cert := &x509.Certificate{
-		SerialNumber: big.NewInt(2019),
-		Subject:      pkix.Name{},
-		DNSNames:     []string{"localhost"},
-		NotBefore:    time.Now(),
-		NotAfter:     time.Now().Add(validTime),
-		SubjectKeyId: []byte{1, 2, 3, 4, 6},
-	}
-
-	certBytes, err := x509.CreateCertificate(rand.Reader, cert, ca, &privateKey.PublicKey, privateKey)
-	if err != nil {
-		return ds, err
-	}
-
-	certPEM := new(bytes.Buffer)
-	err = pem.Encode(certPEM, &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: certBytes,
-	})
-	if err != nil {
-		return ds, err
-	}
-
-	certPrivKeyPEM := new(bytes.Buffer)
-
-	err = pem.Encode(certPrivKeyPEM, &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	})
-	if err != nil {
-		return ds, err
-	}
-
-	ds.Crt = certPEM.Bytes()
-	ds.Key = certPrivKeyPEM.Bytes()
-	ds.CA = caCertPEM
-
-	return ds, nil
-}
-
-type mockCredentialsFetcher struct {
-	ds        credentialsmanager.DataStore
-	callCount int
-}
-
-func (mock *mockCredentialsFetcher) GetDataStore(ctx context.Context, secretsName string) (*credentialsmanager.DataStore, error) {
-	mock.callCount += 1
-	return &mock.ds, nil
-}
-
-func loadTLSCredentials(ds credentialsmanager.DataStore) (credentials.TransportCredentials, error) {
-	certPool := x509.NewCertPool()
-	if !certPool.AppendCertsFromPEM(ds.CA) {
-		return nil, fmt.Errorf("failed to add CA certificate")
-	}
-
-	serverCert, err := tls.X509KeyPair(ds.Crt, ds.Key)
-	if err != nil {
-		return nil, err
-	}
-
-	config := &tls.Config{
-		Certificates: []tls.Certificate{serverCert},
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		ClientCAs:    certPool,
-		MinVersion:   tls.VersionTLS13,
-	}
-
-	return credentials.NewTLS(config), nil
+	require.Equal(t, 2, cf.calls, "Cached certificates are still valid and should not be reloaded")
 }
 
 type dummyAuthorizeServer struct {
@@ -273,15 +167,6 @@ func (srv *dummyAuthorizeServer) GetResource(context.Context, *authorizeproto.Ge
 	}, nil
 }
 
-func parseRSAKey() (*rsa.PrivateKey, error) {
-	pemBlock, _ := pem.Decode(rsaKey)
-	k, err := x509.ParsePKCS8PrivateKey(pemBlock.Bytes)
-	if err != nil {
-		return nil, err
-	}
-	return k.(*rsa.PrivateKey), nil
-}
-
 type server struct {
 	signal          chan struct{}
 	tlsCredentials  credentials.TransportCredentials
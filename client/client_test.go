@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync"
 	"testing"
 	"time"
 
@@ -37,6 +38,7 @@ func clientForFakeHostName(t *testing.T, authority, host, port string, _ ...*aut
 type dnsServer struct {
 	dns.Server
 	domain  string
+	mu      sync.Mutex
 	records map[string][]string
 	host    string
 	port    string
@@ -71,6 +73,9 @@ func (d *dnsServer) Stop(t *testing.T) {
 }
 
 func (d *dnsServer) AddEndpoint(domain, ip string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	key := domain + "."
 	if _, ok := d.records[key]; !ok {
 		d.records[key] = []string{}
@@ -92,7 +97,10 @@ func (d *dnsServer) handleDnsRequest(w dns.ResponseWriter, r *dns.Msg) {
 		for _, q := range m.Question {
 			switch q.Qtype {
 			case dns.TypeA:
+				d.mu.Lock()
 				ips := d.records[q.Name]
+				d.mu.Unlock()
+
 				log.WithFields(log.Fields{
 					zap.String("name", q.Name),
 					zap.Strings("records", ips),
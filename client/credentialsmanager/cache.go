@@ -0,0 +1,225 @@
+package credentialsmanager
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SKF/go-utility/v2/log"
+	"github.com/pkg/errors"
+)
+
+const DefaultCacheTTL = 5 * time.Minute
+
+// CacheConfig configures a CachingFetcher.
+type CacheConfig struct {
+	// TTL is how long a fetched DataStore is served from the cache,
+	// DefaultCacheTTL if zero.
+	TTL time.Duration
+	// StaleWindow is how long after the TTL an expired DataStore is still
+	// served if fetching a new one fails. Stale data is never served if zero.
+	StaleWindow time.Duration
+	// OnFetch, if set, is called after every call to the underlying fetcher.
+	OnFetch func(secretsName string, latency time.Duration, err error)
+}
+
+// CacheStats are the counters of a CachingFetcher.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	StaleHits uint64
+	Fetches   uint64
+	Failures  uint64
+	// FetchLatency is the total time spent in the underlying fetcher, and
+	// LastFetchLatency the time spent in the latest call to it.
+	FetchLatency     time.Duration
+	LastFetchLatency time.Duration
+}
+
+type cacheKey struct {
+	secretsName  string
+	versionStage string
+}
+
+type cacheEntry struct {
+	mu         sync.Mutex
+	ds         *DataStore
+	fetchedAt  time.Time
+	generation uint64
+}
+
+// CachingFetcher is a CredentialsFetcher caching the DataStore of every
+// secret, so that many clients in one process share a single fetch.
+//
+// It implements Watcher, and its generation increases on every invalidation
+// as well as whenever the generation of the underlying fetcher does, which
+// makes the transport credentials reload the certificates.
+type CachingFetcher struct {
+	cf     CredentialsFetcher
+	config CacheConfig
+
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry
+
+	invalidations atomic.Uint64
+
+	statsMu sync.Mutex
+	stats   CacheStats
+}
+
+var _ CredentialsFetcher = &CachingFetcher{}
+var _ StagedFetcher = &CachingFetcher{}
+var _ Watcher = &CachingFetcher{}
+
+func NewCachingFetcher(cf CredentialsFetcher, config CacheConfig) *CachingFetcher {
+	if config.TTL <= 0 {
+		config.TTL = DefaultCacheTTL
+	}
+
+	return &CachingFetcher{
+		cf:      cf,
+		config:  config,
+		entries: make(map[cacheKey]*cacheEntry),
+	}
+}
+
+func (c *CachingFetcher) GetDataStore(ctx context.Context, secretsName string) (*DataStore, error) {
+	return c.get(ctx, secretsName, "", func() (*DataStore, error) {
+		return c.cf.GetDataStore(ctx, secretsName)
+	})
+}
+
+// GetDataStoreVersion reads and caches the given version stage of the
+// secret. The underlying fetcher must implement StagedFetcher.
+func (c *CachingFetcher) GetDataStoreVersion(ctx context.Context, secretsName, versionStage string) (*DataStore, error) {
+	staged, ok := c.cf.(StagedFetcher)
+	if !ok {
+		return nil, errors.New("the underlying credentials fetcher doesn't support version stages")
+	}
+
+	return c.get(ctx, secretsName, versionStage, func() (*DataStore, error) {
+		return staged.GetDataStoreVersion(ctx, secretsName, versionStage)
+	})
+}
+
+func (c *CachingFetcher) get(ctx context.Context, secretsName, versionStage string, fetch func() (*DataStore, error)) (*DataStore, error) {
+	entry := c.entry(cacheKey{secretsName: secretsName, versionStage: versionStage})
+
+	// Concurrent callers for the same secret wait for a single fetch.
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	now := time.Now()
+	generation := c.underlyingGeneration()
+
+	if entry.ds != nil && entry.generation == generation && now.Before(entry.fetchedAt.Add(c.config.TTL)) {
+		c.count(func(s *CacheStats) { s.Hits++ })
+		return copyDataStore(entry.ds), nil
+	}
+
+	c.count(func(s *CacheStats) { s.Misses++ })
+
+	ds, err := c.fetch(secretsName, fetch)
+	if err != nil {
+		if entry.ds == nil || !now.Before(entry.fetchedAt.Add(c.config.TTL+c.config.StaleWindow)) {
+			return nil, err
+		}
+
+		log.WithField("secretsName", secretsName).
+			WithTracing(ctx).WithError(err).
+			Warn("serving stale credentials")
+
+		c.count(func(s *CacheStats) { s.StaleHits++ })
+
+		return copyDataStore(entry.ds), nil
+	}
+
+	entry.ds = ds
+	entry.fetchedAt = now
+	entry.generation = generation
+
+	return copyDataStore(ds), nil
+}
+
+// Invalidate drops the cached DataStores of the given secret, forcing the
+// next call to fetch it.
+func (c *CachingFetcher) Invalidate(secretsName string) {
+	c.mu.Lock()
+	for key := range c.entries {
+		if key.secretsName == secretsName {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+
+	c.invalidations.Add(1)
+}
+
+// Generation returns a number which increases on every invalidation and on
+// every change reported by the underlying fetcher.
+func (c *CachingFetcher) Generation() uint64 {
+	return c.invalidations.Load() + c.underlyingGeneration()
+}
+
+func (c *CachingFetcher) Stats() CacheStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	return c.stats
+}
+
+func (c *CachingFetcher) entry(key cacheKey) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &cacheEntry{}
+		c.entries[key] = entry
+	}
+
+	return entry
+}
+
+func (c *CachingFetcher) fetch(secretsName string, fetch func() (*DataStore, error)) (*DataStore, error) {
+	start := time.Now()
+	ds, err := fetch()
+	latency := time.Since(start)
+
+	c.count(func(s *CacheStats) {
+		s.Fetches++
+		s.FetchLatency += latency
+		s.LastFetchLatency = latency
+
+		if err != nil {
+			s.Failures++
+		}
+	})
+
+	if c.config.OnFetch != nil {
+		c.config.OnFetch(secretsName, latency, err)
+	}
+
+	return ds, err
+}
+
+func (c *CachingFetcher) underlyingGeneration() uint64 {
+	if w, ok := c.cf.(Watcher); ok {
+		return w.Generation()
+	}
+
+	return 0
+}
+
+func (c *CachingFetcher) count(f func(*CacheStats)) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	f(&c.stats)
+}
+
+func copyDataStore(ds *DataStore) *DataStore {
+	out := *ds
+	return &out
+}
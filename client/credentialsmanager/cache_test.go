@@ -0,0 +1,126 @@
+package credentialsmanager_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubFetcher struct {
+	mu    sync.Mutex
+	crt   string
+	err   error
+	calls int
+}
+
+func (f *stubFetcher) GetDataStore(ctx context.Context, secretsName string) (*credentialsmanager.DataStore, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &credentialsmanager.DataStore{Crt: []byte(secretsName + "/" + f.crt)}, nil
+}
+
+func (f *stubFetcher) set(crt string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.crt, f.err = crt, err
+}
+
+func Test_CachingFetcher_CachesPerSecret(t *testing.T) {
+	ctx := context.Background()
+	stub := &stubFetcher{crt: "v1"}
+	c := credentialsmanager.NewCachingFetcher(stub, credentialsmanager.CacheConfig{})
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ds, err := c.GetDataStore(ctx, "a")
+			assert.NoError(t, err)
+			assert.Equal(t, "a/v1", string(ds.Crt))
+		}()
+	}
+	wg.Wait()
+
+	ds, err := c.GetDataStore(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, "b/v1", string(ds.Crt))
+
+	assert.Equal(t, 2, stub.calls, "A single fetch per secret")
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(49), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, uint64(2), stats.Fetches)
+}
+
+func Test_CachingFetcher_StaleWindow(t *testing.T) {
+	ctx := context.Background()
+	fetchErr := errors.New("throttled")
+	stub := &stubFetcher{crt: "v1"}
+
+	var failures int
+	c := credentialsmanager.NewCachingFetcher(stub, credentialsmanager.CacheConfig{
+		TTL:         10 * time.Millisecond,
+		StaleWindow: 50 * time.Millisecond,
+		OnFetch: func(_ string, _ time.Duration, err error) {
+			if err != nil {
+				failures++
+			}
+		},
+	})
+
+	_, err := c.GetDataStore(ctx, "a")
+	require.NoError(t, err)
+
+	stub.set("v2", fetchErr)
+	time.Sleep(20 * time.Millisecond)
+
+	ds, err := c.GetDataStore(ctx, "a")
+	require.NoError(t, err, "Stale data is served within the window")
+	assert.Equal(t, "a/v1", string(ds.Crt))
+
+	time.Sleep(50 * time.Millisecond)
+
+	_, err = c.GetDataStore(ctx, "a")
+	require.ErrorIs(t, err, fetchErr, "Stale data is not served after the window")
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.StaleHits)
+	assert.Equal(t, uint64(2), stats.Failures)
+	assert.Equal(t, 2, failures)
+}
+
+func Test_CachingFetcher_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	stub := &stubFetcher{crt: "v1"}
+	c := credentialsmanager.NewCachingFetcher(stub, credentialsmanager.CacheConfig{})
+
+	_, err := c.GetDataStore(ctx, "a")
+	require.NoError(t, err)
+
+	generation := c.Generation()
+	stub.set("v2", nil)
+	c.Invalidate("a")
+
+	assert.Greater(t, c.Generation(), generation, "Invalidations make the transport credentials reload")
+
+	ds, err := c.GetDataStore(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a/v2", string(ds.Crt))
+}
@@ -0,0 +1,249 @@
+package credentialsmanager
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SKF/go-utility/v2/log"
+	"github.com/pkg/errors"
+)
+
+const DefaultPollInterval = 10 * time.Second
+
+// maxReadAttempts is how many times the files are read when they change while
+// being read.
+const maxReadAttempts = 3
+
+// Watcher is implemented by fetchers which know when the credentials they
+// return have changed. The generation increases on every change, which makes
+// the transport credentials reload them on the next handshake.
+type Watcher interface {
+	Generation() uint64
+}
+
+// FilePaths are the paths of the PEM encoded CA, key and certificate.
+type FilePaths struct {
+	CA  string
+	Key string
+	Crt string
+}
+
+type FileOption func(*FileFetcher)
+
+// WithFileFormat sets the format of the JSON file read by a fetcher created
+// with NewJSONFileFetcher, FormatAuto by default.
+func WithFileFormat(format Format) FileOption {
+	return func(f *FileFetcher) {
+		f.format = format
+	}
+}
+
+// WithPollInterval sets how often the files are checked for changes,
+// DefaultPollInterval by default. The interval must be positive.
+func WithPollInterval(d time.Duration) FileOption {
+	return func(f *FileFetcher) {
+		f.pollInterval = d
+	}
+}
+
+// FileFetcher is a CredentialsFetcher reading the credentials from files,
+// e.g. mounted from a Kubernetes secret. The files are polled for changes
+// rather than watched with inotify, as watches on projected volumes are lost
+// on every atomic symlink swap. Comparing the contents covers those swaps, at
+// the cost of noticing a change up to one poll interval late, i.e. 10 seconds
+// by default. The fetcher must be closed once it is no longer used, as the
+// files are polled by a goroutine until then.
+type FileFetcher struct {
+	paths        FilePaths
+	jsonPath     string
+	format       Format
+	pollInterval time.Duration
+
+	generation atomic.Uint64
+	contents   [][]byte
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+var _ CredentialsFetcher = &FileFetcher{}
+var _ Watcher = &FileFetcher{}
+
+// NewFileFetcher creates a fetcher reading the CA, key and certificate from
+// separate PEM files. It must be closed with Close.
+func NewFileFetcher(paths FilePaths, opts ...FileOption) (*FileFetcher, error) {
+	return newFileFetcher(&FileFetcher{paths: paths}, opts)
+}
+
+// NewJSONFileFetcher creates a fetcher reading a single JSON file in the
+// DataStore format, as stored in Secrets Manager. It must be closed with
+// Close.
+func NewJSONFileFetcher(path string, opts ...FileOption) (*FileFetcher, error) {
+	return newFileFetcher(&FileFetcher{jsonPath: path}, opts)
+}
+
+func newFileFetcher(f *FileFetcher, opts []FileOption) (*FileFetcher, error) {
+	f.pollInterval = DefaultPollInterval
+	f.stop = make(chan struct{})
+	f.done = make(chan struct{})
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	if f.pollInterval <= 0 {
+		return nil, errors.Errorf("poll interval must be positive, got %s", f.pollInterval)
+	}
+
+	contents, err := f.readFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	f.contents = contents
+
+	go f.poll()
+
+	return f, nil
+}
+
+// GetDataStore reads the credentials from the files. The secret name is
+// ignored, as the files are given when the fetcher is created.
+func (f *FileFetcher) GetDataStore(ctx context.Context, secretsName string) (*DataStore, error) {
+	contents, err := f.readFiles()
+	if err != nil {
+		log.WithTracing(ctx).WithError(err).
+			Error("failed to read credentials files")
+		return nil, err
+	}
+
+	if f.jsonPath == "" {
+		return &DataStore{CA: contents[0], Key: contents[1], Crt: contents[2]}, nil
+	}
+
+	out, err := ParseDataStore(contents[0], f.format)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal credentials from '%s'", f.jsonPath)
+	}
+
+	return out, nil
+}
+
+// Generation returns a number which increases every time the files change.
+func (f *FileFetcher) Generation() uint64 {
+	return f.generation.Load()
+}
+
+// Close stops the goroutine watching the files.
+func (f *FileFetcher) Close() error {
+	f.stopOnce.Do(func() { close(f.stop) })
+	<-f.done
+
+	return nil
+}
+
+func (f *FileFetcher) files() []string {
+	if f.jsonPath != "" {
+		return []string{f.jsonPath}
+	}
+
+	return []string{f.paths.CA, f.paths.Key, f.paths.Crt}
+}
+
+// readFiles reads the files, making sure that they didn't change while being
+// read so that the CA, key and certificate are of the same rotation.
+func (f *FileFetcher) readFiles() ([][]byte, error) {
+	files := f.files()
+
+	for attempt := 0; attempt < maxReadAttempts; attempt++ {
+		before, err := statFiles(files)
+		if err != nil {
+			return nil, err
+		}
+
+		contents := make([][]byte, len(files))
+		for i, name := range files {
+			if contents[i], err = os.ReadFile(name); err != nil {
+				return nil, errors.Wrapf(err, "failed to read credentials from '%s'", name)
+			}
+		}
+
+		after, err := statFiles(files)
+		if err != nil {
+			return nil, err
+		}
+
+		if sameFiles(before, after) {
+			return contents, nil
+		}
+	}
+
+	return nil, errors.Errorf("credentials files changed while being read %d times", maxReadAttempts)
+}
+
+func statFiles(files []string) ([]os.FileInfo, error) {
+	infos := make([]os.FileInfo, len(files))
+
+	for i, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read credentials from '%s'", name)
+		}
+
+		infos[i] = info
+	}
+
+	return infos, nil
+}
+
+// sameFiles reports whether the files weren't replaced, e.g. by a symlink
+// swap, or modified in between the two stats.
+func sameFiles(a, b []os.FileInfo) bool {
+	for i := range a {
+		if !os.SameFile(a[i], b[i]) || !a[i].ModTime().Equal(b[i].ModTime()) || a[i].Size() != b[i].Size() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f *FileFetcher) poll() {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+		}
+
+		// Files which can't be read, e.g. in the middle of a swap, are
+		// checked again on the next tick.
+		contents, err := f.readFiles()
+		if err != nil || equalContents(contents, f.contents) {
+			continue
+		}
+
+		f.contents = contents
+		f.generation.Add(1)
+	}
+}
+
+func equalContents(a, b [][]byte) bool {
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
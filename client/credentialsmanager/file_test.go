@@ -0,0 +1,158 @@
+package credentialsmanager_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeVersion writes the files into a new directory and points the data
+// symlink at it, the same way Kubernetes updates projected volumes.
+func writeVersion(t *testing.T, dir, version string, files map[string]string) {
+	versionDir := filepath.Join(dir, version)
+	require.NoError(t, os.Mkdir(versionDir, 0o755))
+
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(versionDir, name), []byte(content), 0o600))
+	}
+
+	tmp := filepath.Join(dir, "..data_tmp")
+	require.NoError(t, os.Symlink(versionDir, tmp))
+	require.NoError(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+}
+
+func waitForGeneration(t *testing.T, w credentialsmanager.Watcher, generation uint64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for w.Generation() < generation {
+		if time.Now().After(deadline) {
+			t.Fatal("The change was never detected")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func Test_FileFetcher_SymlinkSwap(t *testing.T) {
+	dir := t.TempDir()
+
+	writeVersion(t, dir, "v1", map[string]string{"ca.crt": "ca1", "tls.key": "key1", "tls.crt": "crt1"})
+
+	for _, name := range []string{"ca.crt", "tls.key", "tls.crt"} {
+		require.NoError(t, os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)))
+	}
+
+	f, err := credentialsmanager.NewFileFetcher(credentialsmanager.FilePaths{
+		CA:  filepath.Join(dir, "ca.crt"),
+		Key: filepath.Join(dir, "tls.key"),
+		Crt: filepath.Join(dir, "tls.crt"),
+	}, credentialsmanager.WithPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer f.Close()
+
+	ds, err := f.GetDataStore(context.Background(), "ignored")
+	require.NoError(t, err)
+	assert.Equal(t, "crt1", string(ds.Crt))
+	assert.Equal(t, uint64(0), f.Generation())
+
+	writeVersion(t, dir, "v2", map[string]string{"ca.crt": "ca1", "tls.key": "key2", "tls.crt": "crt2"})
+	waitForGeneration(t, f, 1)
+
+	ds, err = f.GetDataStore(context.Background(), "ignored")
+	require.NoError(t, err)
+	assert.Equal(t, "key2", string(ds.Key))
+	assert.Equal(t, "crt2", string(ds.Crt))
+}
+
+func Test_JSONFileFetcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	write := func(ds credentialsmanager.DataStore) {
+		b, err := json.Marshal(ds)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, b, 0o600))
+	}
+
+	write(credentialsmanager.DataStore{CA: []byte("ca"), Key: []byte("key1"), Crt: []byte("crt1")})
+
+	f, err := credentialsmanager.NewJSONFileFetcher(path, credentialsmanager.WithPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer f.Close()
+
+	write(credentialsmanager.DataStore{CA: []byte("ca"), Key: []byte("key2"), Crt: []byte("crt2")})
+	waitForGeneration(t, f, 1)
+
+	ds, err := f.GetDataStore(context.Background(), "ignored")
+	require.NoError(t, err)
+	assert.Equal(t, "crt2", string(ds.Crt))
+}
+
+func Test_FileFetcher_MissingFile(t *testing.T) {
+	_, err := credentialsmanager.NewJSONFileFetcher(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func Test_FileFetcher_InvalidPollInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{}`), 0o600))
+
+	for _, d := range []time.Duration{0, -time.Second} {
+		_, err := credentialsmanager.NewJSONFileFetcher(path, credentialsmanager.WithPollInterval(d))
+		assert.Error(t, err, "A poll interval of %s must be rejected", d)
+	}
+}
+
+func Test_FileFetcher_ConsistentReads(t *testing.T) {
+	dir := t.TempDir()
+
+	writeVersion(t, dir, "v0", map[string]string{"ca.crt": "ca0", "tls.key": "key0", "tls.crt": "crt0"})
+
+	for _, name := range []string{"ca.crt", "tls.key", "tls.crt"} {
+		require.NoError(t, os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)))
+	}
+
+	f, err := credentialsmanager.NewFileFetcher(credentialsmanager.FilePaths{
+		CA:  filepath.Join(dir, "ca.crt"),
+		Key: filepath.Join(dir, "tls.key"),
+		Crt: filepath.Join(dir, "tls.crt"),
+	})
+	require.NoError(t, err)
+	defer f.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for i := 1; i <= 200; i++ {
+			v := strconv.Itoa(i)
+			writeVersion(t, dir, "v"+v, map[string]string{"ca.crt": "ca" + v, "tls.key": "key" + v, "tls.crt": "crt" + v})
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		ds, err := f.GetDataStore(context.Background(), "ignored")
+		if err != nil {
+			// Reading may give up while the files keep changing.
+			continue
+		}
+
+		version := strings.TrimPrefix(string(ds.Crt), "crt")
+		require.Equal(t, "key"+version, string(ds.Key), "The files are of the same rotation")
+		require.Equal(t, "ca"+version, string(ds.CA), "The files are of the same rotation")
+	}
+}
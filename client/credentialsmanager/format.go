@@ -0,0 +1,81 @@
+package credentialsmanager
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Format is the encoding of a secret holding a DataStore.
+type Format int
+
+const (
+	// FormatAuto detects the encoding of every field of the JSON document.
+	FormatAuto Format = iota
+	// FormatBase64JSON is a JSON document with base64 encoded PEM fields,
+	// which is how a DataStore is marshalled.
+	FormatBase64JSON
+	// FormatPEMJSON is a JSON document with PEM strings as fields.
+	FormatPEMJSON
+)
+
+var pemPrefix = []byte("-----BEGIN")
+
+// ParseDataStore parses a JSON document with the fields ca, key and crt, in
+// the given format. Binary secrets hold the same document as string secrets.
+func ParseDataStore(data []byte, format Format) (*DataStore, error) {
+	var fields struct {
+		CA  *string `json:"ca"`
+		Key *string `json:"key"`
+		Crt *string `json:"crt"`
+	}
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal credentials")
+	}
+
+	var (
+		out DataStore
+		err error
+	)
+
+	if out.CA, err = decodeField("ca", fields.CA, format); err != nil {
+		return nil, err
+	}
+
+	if out.Key, err = decodeField("key", fields.Key, format); err != nil {
+		return nil, err
+	}
+
+	if out.Crt, err = decodeField("crt", fields.Crt, format); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+func decodeField(name string, value *string, format Format) ([]byte, error) {
+	// Missing fields are left empty and reported by Validate.
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+
+	raw := []byte(*value)
+	isPEM := bytes.HasPrefix(bytes.TrimSpace(raw), pemPrefix)
+
+	switch {
+	case format == FormatPEMJSON && !isPEM:
+		return nil, errors.Errorf("credentials field '%s' is not a PEM string", name)
+	case format == FormatPEMJSON, format == FormatAuto && isPEM:
+		return raw, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(*value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "credentials field '%s' is neither PEM nor base64 encoded", name)
+	}
+
+	return decoded, nil
+}
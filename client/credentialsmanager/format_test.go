@@ -0,0 +1,66 @@
+package credentialsmanager_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseDataStore(t *testing.T) {
+	now := time.Now()
+	ds := newIssuer(t).issue(t, now.Add(-time.Minute), now.Add(time.Minute))
+
+	base64JSON, err := json.Marshal(ds)
+	require.NoError(t, err)
+
+	pemJSON, err := json.Marshal(map[string]string{"ca": string(ds.CA), "key": string(ds.Key), "crt": string(ds.Crt)})
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		data   []byte
+		format credentialsmanager.Format
+		err    bool
+	}{
+		"auto base64":         {data: base64JSON, format: credentialsmanager.FormatAuto},
+		"auto PEM":            {data: pemJSON, format: credentialsmanager.FormatAuto},
+		"base64":              {data: base64JSON, format: credentialsmanager.FormatBase64JSON},
+		"PEM":                 {data: pemJSON, format: credentialsmanager.FormatPEMJSON},
+		"base64 given PEM":    {data: pemJSON, format: credentialsmanager.FormatBase64JSON, err: true},
+		"PEM given base64":    {data: base64JSON, format: credentialsmanager.FormatPEMJSON, err: true},
+		"not JSON":            {data: ds.Crt, format: credentialsmanager.FormatAuto, err: true},
+		"neither PEM nor b64": {data: []byte(`{"crt": "not base64!"}`), format: credentialsmanager.FormatAuto, err: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			out, err := credentialsmanager.ParseDataStore(tc.data, tc.format)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, ds.CA, out.CA)
+			assert.Equal(t, ds.Key, out.Key)
+			assert.Equal(t, ds.Crt, out.Crt)
+		})
+	}
+
+	mixed := `{"ca": ` + string(mustMarshal(t, string(ds.CA))) + `, "crt": "` + base64.StdEncoding.EncodeToString(ds.Crt) + `"}`
+	out, err := credentialsmanager.ParseDataStore([]byte(mixed), credentialsmanager.FormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, ds.CA, out.CA)
+	assert.Equal(t, ds.Crt, out.Crt)
+	assert.Empty(t, out.Key)
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	return b
+}
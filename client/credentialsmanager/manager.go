@@ -2,7 +2,6 @@ package credentialsmanager
 
 import (
 	"context"
-	"encoding/json"
 
 	"github.com/SKF/go-utility/v2/log"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -13,32 +12,82 @@ import (
 type SMAPI interface {
 	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
 }
+
+// Secrets Manager version stages.
+const (
+	VersionStageCurrent  = "AWSCURRENT"
+	VersionStagePending  = "AWSPENDING"
+	VersionStagePrevious = "AWSPREVIOUS"
+)
+
 type DataStore struct {
 	CA  []byte `json:"ca"`
 	Key []byte `json:"key"`
 	Crt []byte `json:"crt"`
+
+	// VersionID and VersionStage identify the secret version the DataStore
+	// was read from, if known.
+	VersionID    string `json:"-"`
+	VersionStage string `json:"-"`
 }
 
 type credentialsManager struct {
-	sm SMAPI
+	sm           SMAPI
+	versionStage string
+	format       Format
 }
 
 type CredentialsFetcher interface {
 	GetDataStore(ctx context.Context, secretsName string) (*DataStore, error)
 }
 
-func New(sm SMAPI) *credentialsManager {
-	return &credentialsManager{sm: sm}
+// StagedFetcher is implemented by fetchers which can read a specific version
+// stage of a secret.
+type StagedFetcher interface {
+	CredentialsFetcher
+	GetDataStoreVersion(ctx context.Context, secretsName, versionStage string) (*DataStore, error)
+}
+
+type Option func(*credentialsManager)
+
+// WithVersionStage sets the version stage read by GetDataStore,
+// VersionStageCurrent by default.
+func WithVersionStage(versionStage string) Option {
+	return func(cm *credentialsManager) {
+		cm.versionStage = versionStage
+	}
+}
+
+// WithSecretFormat sets the format of the secrets, FormatAuto by default.
+func WithSecretFormat(format Format) Option {
+	return func(cm *credentialsManager) {
+		cm.format = format
+	}
+}
+
+func New(sm SMAPI, opts ...Option) *credentialsManager {
+	cm := &credentialsManager{sm: sm, versionStage: VersionStageCurrent}
+
+	for _, opt := range opts {
+		opt(cm)
+	}
+
+	return cm
 }
 
 func (cm *credentialsManager) GetDataStore(ctx context.Context, secretsName string) (*DataStore, error) {
+	return cm.GetDataStoreVersion(ctx, secretsName, cm.versionStage)
+}
+
+func (cm *credentialsManager) GetDataStoreVersion(ctx context.Context, secretsName, versionStage string) (*DataStore, error) {
 	input := &secretsmanager.GetSecretValueInput{
 		SecretId:     aws.String(secretsName),
-		VersionStage: aws.String("AWSCURRENT"),
+		VersionStage: aws.String(versionStage),
 	}
 
 	logger := log.
 		WithField("secretsName", secretsName).
+		WithField("versionStage", versionStage).
 		WithField("credentialsManager", "V2")
 
 	result, err := cm.sm.GetSecretValue(ctx, input)
@@ -49,14 +98,30 @@ func (cm *credentialsManager) GetDataStore(ctx context.Context, secretsName stri
 		return nil, err
 	}
 
-	var out DataStore
+	var secret []byte
+
+	switch {
+	case result.SecretString != nil:
+		secret = []byte(*result.SecretString)
+	case result.SecretBinary != nil:
+		secret = result.SecretBinary
+	default:
+		err = errors.Errorf("secret '%s' has neither a string nor a binary value", secretsName)
+		logger.WithTracing(ctx).WithError(err).
+			Error("failed to get secrets")
+		return nil, err
+	}
 
-	if err = json.Unmarshal([]byte(*result.SecretString), &out); err != nil {
+	out, err := ParseDataStore(secret, cm.format)
+	if err != nil {
 		logger.WithTracing(ctx).WithError(err).
 			Error("failed to unmarshal secret")
 		err = errors.Wrapf(err, "failed to unmarshal secret from '%s'", secretsName)
 		return nil, err
 	}
 
-	return &out, err
+	out.VersionID = aws.ToString(result.VersionId)
+	out.VersionStage = versionStage
+
+	return out, nil
 }
@@ -0,0 +1,72 @@
+package credentialsmanager_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSecretsManager struct {
+	versionStages []string
+}
+
+func (sm *fakeSecretsManager) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	stage := aws.ToString(params.VersionStage)
+	sm.versionStages = append(sm.versionStages, stage)
+
+	return &secretsmanager.GetSecretValueOutput{
+		SecretString: aws.String(`{"crt": "Y3J0"}`),
+		VersionId:    aws.String("id-" + stage),
+	}, nil
+}
+
+func Test_Manager_VersionStage(t *testing.T) {
+	ctx := context.Background()
+	sm := &fakeSecretsManager{}
+
+	ds, err := credentialsmanager.New(sm).GetDataStore(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "crt", string(ds.Crt))
+	assert.Equal(t, credentialsmanager.VersionStageCurrent, ds.VersionStage)
+	assert.Equal(t, "id-AWSCURRENT", ds.VersionID)
+
+	ds, err = credentialsmanager.New(sm, credentialsmanager.WithVersionStage(credentialsmanager.VersionStagePending)).GetDataStore(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, credentialsmanager.VersionStagePending, ds.VersionStage)
+
+	assert.Equal(t, []string{"AWSCURRENT", "AWSPENDING"}, sm.versionStages)
+}
+
+type rawSecretsManager struct {
+	output secretsmanager.GetSecretValueOutput
+}
+
+func (sm rawSecretsManager) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	return &sm.output, nil
+}
+
+func Test_Manager_SecretFormats(t *testing.T) {
+	ctx := context.Background()
+	pemJSON := `{"ca": "-----BEGIN CERTIFICATE-----\nca\n-----END CERTIFICATE-----\n", "crt": "Y3J0"}`
+
+	ds, err := credentialsmanager.New(rawSecretsManager{secretsmanager.GetSecretValueOutput{SecretBinary: []byte(`{"crt": "Y3J0"}`)}}).GetDataStore(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "crt", string(ds.Crt))
+
+	ds, err = credentialsmanager.New(rawSecretsManager{secretsmanager.GetSecretValueOutput{SecretString: aws.String(pemJSON)}}).GetDataStore(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----\nca\n-----END CERTIFICATE-----\n", string(ds.CA))
+	assert.Equal(t, "crt", string(ds.Crt))
+
+	_, err = credentialsmanager.New(rawSecretsManager{secretsmanager.GetSecretValueOutput{SecretString: aws.String(pemJSON)}}, credentialsmanager.WithSecretFormat(credentialsmanager.FormatPEMJSON)).GetDataStore(ctx, "secret")
+	assert.ErrorContains(t, err, "'crt' is not a PEM string")
+
+	_, err = credentialsmanager.New(rawSecretsManager{}).GetDataStore(ctx, "secret")
+	assert.ErrorContains(t, err, "neither a string nor a binary value")
+}
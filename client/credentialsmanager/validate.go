@@ -0,0 +1,215 @@
+package credentialsmanager
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of validation errors, matched with errors.Is.
+var (
+	ErrInvalidCA            = errors.New("invalid CA")
+	ErrInvalidCertificate   = errors.New("invalid certificate")
+	ErrInvalidKey           = errors.New("invalid private key")
+	ErrKeyMismatch          = errors.New("private key doesn't match the certificate")
+	ErrNotYetValid          = errors.New("certificate is not valid yet")
+	ErrExpired              = errors.New("certificate has expired")
+	ErrKeyUsage             = errors.New("certificate extended key usage doesn't allow its use")
+	ErrUntrustedCertificate = errors.New("certificate doesn't chain to the CA")
+)
+
+// ValidationError is returned by Validate. It unwraps to one of the kinds of
+// validation errors as well as to the underlying error, if any.
+type ValidationError struct {
+	Kind   error
+	Detail string
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	msg := "invalid credentials: " + e.Kind.Error()
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *ValidationError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+
+	return []error{e.Kind}
+}
+
+// CertificateInfo describes the certificate of a DataStore.
+type CertificateInfo struct {
+	Subject      string
+	Issuer       string
+	DNSNames     []string
+	SerialNumber string
+	NotBefore    time.Time
+	NotAfter     time.Time
+}
+
+// Validate checks that the DataStore holds a certificate usable by a client:
+// the key matches the certificate, which is currently valid, allows client
+// authentication and chains to the CA. The certificate is described as soon
+// as it could be parsed, also when it is invalid.
+func (ds DataStore) Validate() (CertificateInfo, error) {
+	return ds.ValidateUsage(x509.ExtKeyUsageClientAuth)
+}
+
+// ValidateUsage is Validate for a certificate used for the given purpose,
+// e.g. x509.ExtKeyUsageServerAuth for a server.
+func (ds DataStore) ValidateUsage(usage x509.ExtKeyUsage) (CertificateInfo, error) {
+	chain, err := parseCertificates(ds.Crt)
+	if err != nil {
+		return CertificateInfo{}, &ValidationError{Kind: ErrInvalidCertificate, Err: err}
+	}
+
+	leaf := chain[0]
+	info := CertificateInfo{
+		Subject:      leaf.Subject.String(),
+		Issuer:       leaf.Issuer.String(),
+		DNSNames:     leaf.DNSNames,
+		SerialNumber: leaf.SerialNumber.String(),
+		NotBefore:    leaf.NotBefore,
+		NotAfter:     leaf.NotAfter,
+	}
+
+	key, err := parsePrivateKey(ds.Key)
+	if err != nil {
+		return info, &ValidationError{Kind: ErrInvalidKey, Err: err}
+	}
+
+	if !key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(leaf.PublicKey) {
+		return info, &ValidationError{Kind: ErrKeyMismatch}
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ds.CA) {
+		return info, &ValidationError{Kind: ErrInvalidCA, Detail: "no certificates found"}
+	}
+
+	now := time.Now()
+
+	if now.Before(leaf.NotBefore) {
+		return info, &ValidationError{Kind: ErrNotYetValid, Detail: "valid from " + leaf.NotBefore.Format(time.RFC3339)}
+	}
+
+	if now.After(leaf.NotAfter) {
+		return info, &ValidationError{Kind: ErrExpired, Detail: "expired at " + leaf.NotAfter.Format(time.RFC3339)}
+	}
+
+	if len(leaf.ExtKeyUsage) > 0 && !slices.Contains(leaf.ExtKeyUsage, usage) && !slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageAny) {
+		return info, &ValidationError{Kind: ErrKeyUsage, Detail: usageName(usage) + " is required"}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		// The extended key usage of the leaf is checked above.
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return info, &ValidationError{Kind: ErrUntrustedCertificate, Err: err}
+	}
+
+	return info, nil
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append(chain, cert)
+	}
+
+	if len(chain) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+
+	return chain, nil
+}
+
+// parsePrivateKey parses the first private key of the PEM data, in the same
+// formats as tls.X509KeyPair.
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			return nil, errors.New("no PEM data found")
+		}
+
+		if block.Type == "PRIVATE KEY" || strings.HasSuffix(block.Type, " PRIVATE KEY") {
+			return parsePrivateKeyDER(block.Bytes)
+		}
+	}
+}
+
+func parsePrivateKeyDER(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		switch key := key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+			return key.(crypto.Signer), nil
+		}
+
+		return nil, errors.Errorf("unsupported private key type %T", key)
+	}
+
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("failed to parse private key")
+}
+
+func usageName(usage x509.ExtKeyUsage) string {
+	switch usage {
+	case x509.ExtKeyUsageClientAuth:
+		return "client authentication"
+	case x509.ExtKeyUsageServerAuth:
+		return "server authentication"
+	}
+
+	return "extended key usage " + strconv.Itoa(int(usage))
+}
@@ -0,0 +1,80 @@
+package credentialsmanager_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	"github.com/SKF/go-enlight-authorizer/pkitest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type issuer struct {
+	*pkitest.CA
+}
+
+func newIssuer(t *testing.T) issuer {
+	ca, err := pkitest.NewCA(pkitest.WithCommonName("test CA"))
+	require.NoError(t, err)
+
+	return issuer{ca}
+}
+
+func (i issuer) issue(t *testing.T, notBefore, notAfter time.Time, usage ...x509.ExtKeyUsage) credentialsmanager.DataStore {
+	ds, err := i.Issue(pkitest.WithCommonName("client"), pkitest.WithValidity(notBefore, notAfter), pkitest.WithExtKeyUsage(usage...))
+	require.NoError(t, err)
+
+	return ds
+}
+
+func Test_Validate(t *testing.T) {
+	ca := newIssuer(t)
+	now := time.Now()
+
+	valid := ca.issue(t, now.Add(-time.Minute), now.Add(time.Minute), x509.ExtKeyUsageClientAuth)
+
+	info, err := valid.Validate()
+	require.NoError(t, err)
+	assert.Equal(t, "CN=client", info.Subject)
+	assert.Equal(t, "CN=test CA", info.Issuer)
+	assert.WithinDuration(t, now.Add(time.Minute), info.NotAfter, time.Second)
+
+	mismatch := valid
+	mismatch.Key = ca.issue(t, now.Add(-time.Minute), now.Add(time.Minute)).Key
+
+	invalidKey := valid
+	invalidKey.Key = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("not a key")})
+
+	untrusted := valid
+	untrusted.CA = newIssuer(t).PEM
+
+	for name, tc := range map[string]struct {
+		ds   credentialsmanager.DataStore
+		kind error
+	}{
+		"no certificate": {ds: credentialsmanager.DataStore{CA: valid.CA, Key: valid.Key}, kind: credentialsmanager.ErrInvalidCertificate},
+		"no key":         {ds: credentialsmanager.DataStore{CA: valid.CA, Crt: valid.Crt}, kind: credentialsmanager.ErrInvalidKey},
+		"key mismatch":   {ds: mismatch, kind: credentialsmanager.ErrKeyMismatch},
+		"invalid key":    {ds: invalidKey, kind: credentialsmanager.ErrInvalidKey},
+		"no CA":          {ds: credentialsmanager.DataStore{Crt: valid.Crt, Key: valid.Key}, kind: credentialsmanager.ErrInvalidCA},
+		"not yet valid":  {ds: ca.issue(t, now.Add(time.Minute), now.Add(time.Hour)), kind: credentialsmanager.ErrNotYetValid},
+		"expired":        {ds: ca.issue(t, now.Add(-time.Hour), now.Add(-time.Minute)), kind: credentialsmanager.ErrExpired},
+		"server only":    {ds: ca.issue(t, now.Add(-time.Minute), now.Add(time.Minute), x509.ExtKeyUsageServerAuth), kind: credentialsmanager.ErrKeyUsage},
+		"untrusted":      {ds: untrusted, kind: credentialsmanager.ErrUntrustedCertificate},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := tc.ds.Validate()
+			require.ErrorIs(t, err, tc.kind)
+
+			var validationErr *credentialsmanager.ValidationError
+			assert.ErrorAs(t, err, &validationErr)
+		})
+	}
+
+	_, err = ca.issue(t, now.Add(-time.Minute), now.Add(time.Minute), x509.ExtKeyUsageServerAuth).ValidateUsage(x509.ExtKeyUsageServerAuth)
+	assert.NoError(t, err)
+}
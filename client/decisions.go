@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	authorizeApi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+)
+
+type decisionKind uint8
+
+const (
+	decisionIsAuthorized decisionKind = iota
+	decisionWithReason
+	decisionByEndpoint
+)
+
+type originKey struct {
+	id, originType, provider string
+}
+
+func keyOf(origin *common.Origin) originKey {
+	return originKey{
+		id:         origin.GetId(),
+		originType: origin.GetType(),
+		provider:   origin.GetProvider(),
+	}
+}
+
+type decisionKey struct {
+	kind     decisionKind
+	userID   string
+	action   string
+	resource originKey
+	api      string
+	method   string
+	endpoint string
+}
+
+// decisionStore keeps decisions by key, bounded by a least recently used
+// eviction. Every invalidation increases its generation, so that decisions
+// fetched while the store was invalidated are not added, as they might
+// already be stale.
+type decisionStore[V any] struct {
+	mu         sync.Mutex
+	decisions  *lru[decisionKey, V]
+	generation uint64
+}
+
+func newDecisionStore[V any](maxEntries int) *decisionStore[V] {
+	return &decisionStore[V]{
+		decisions: newLRU[decisionKey, V](maxEntries),
+	}
+}
+
+// lookup returns the decision for key if fresh reports it as such, a stale
+// decision is removed. It returns the current generation as well, which must
+// be passed on to add.
+func (s *decisionStore[V]) lookup(key decisionKey, fresh func(V) bool) (V, uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.decisions.Get(key)
+	if ok && fresh(d) {
+		return d, s.generation, true
+	}
+
+	if ok {
+		s.decisions.Remove(key)
+	}
+
+	var zero V
+
+	return zero, s.generation, false
+}
+
+// current returns the current generation, which must be passed on to add.
+func (s *decisionStore[V]) current() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.generation
+}
+
+// add stores the decision unless the store was invalidated since generation
+// was returned. It reports whether another decision was evicted.
+func (s *decisionStore[V]) add(key decisionKey, generation uint64, d V) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if generation != s.generation {
+		return false
+	}
+
+	return s.decisions.Add(key, d)
+}
+
+func (s *decisionStore[V]) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.decisions.Len()
+}
+
+func (s *decisionStore[V]) purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	s.decisions.Purge()
+}
+
+func (s *decisionStore[V]) invalidateUser(userID string) {
+	s.invalidate(func(key decisionKey) bool {
+		return key.userID == userID
+	})
+}
+
+func (s *decisionStore[V]) invalidateResources(resources ...*common.Origin) {
+	keys := make(map[originKey]struct{}, len(resources))
+	for _, resource := range resources {
+		keys[keyOf(resource)] = struct{}{}
+	}
+
+	s.invalidate(func(key decisionKey) bool {
+		_, ok := keys[key.resource]
+		return ok && key.kind != decisionByEndpoint
+	})
+}
+
+func (s *decisionStore[V]) invalidate(match func(decisionKey) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	s.decisions.RemoveFunc(func(key decisionKey, _ V) bool {
+		return match(key)
+	})
+}
+
+// invalidatingClient wraps an AuthorizeClient and invalidates the decisions
+// affected by the writes made through it. Writes made through other clients
+// are not noticed.
+type invalidatingClient[V any] struct {
+	AuthorizeClient
+
+	decisions *decisionStore[V]
+}
+
+func (c *invalidatingClient[V]) AddResource(ctx context.Context, resource *common.Origin) error {
+	defer c.decisions.invalidateResources(resource)
+	return c.AuthorizeClient.AddResource(ctx, resource)
+}
+
+func (c *invalidatingClient[V]) AddResources(ctx context.Context, resources []*common.Origin) error {
+	defer c.decisions.invalidateResources(resources...)
+	return c.AuthorizeClient.AddResources(ctx, resources)
+}
+
+func (c *invalidatingClient[V]) RemoveResource(ctx context.Context, resource *common.Origin) error {
+	defer c.decisions.purge()
+	return c.AuthorizeClient.RemoveResource(ctx, resource)
+}
+
+func (c *invalidatingClient[V]) RemoveResources(ctx context.Context, resources []*common.Origin) error {
+	defer c.decisions.purge()
+	return c.AuthorizeClient.RemoveResources(ctx, resources)
+}
+
+func (c *invalidatingClient[V]) AddResourceRelation(ctx context.Context, resource, parent *common.Origin) error {
+	defer c.decisions.purge()
+	return c.AuthorizeClient.AddResourceRelation(ctx, resource, parent)
+}
+
+func (c *invalidatingClient[V]) AddResourceRelations(ctx context.Context, resources *authorizeApi.AddResourceRelationsInput) error {
+	defer c.decisions.purge()
+	return c.AuthorizeClient.AddResourceRelations(ctx, resources)
+}
+
+func (c *invalidatingClient[V]) RemoveResourceRelation(ctx context.Context, resource, parent *common.Origin) error {
+	defer c.decisions.purge()
+	return c.AuthorizeClient.RemoveResourceRelation(ctx, resource, parent)
+}
+
+func (c *invalidatingClient[V]) RemoveResourceRelations(ctx context.Context, resources *authorizeApi.RemoveResourceRelationsInput) error {
+	defer c.decisions.purge()
+	return c.AuthorizeClient.RemoveResourceRelations(ctx, resources)
+}
+
+func (c *invalidatingClient[V]) ApplyUserAction(ctx context.Context, userID, action string, resource *common.Origin) error {
+	defer c.decisions.invalidateUser(userID)
+	return c.AuthorizeClient.ApplyUserAction(ctx, userID, action, resource)
+}
+
+func (c *invalidatingClient[V]) ApplyRolesForUserOnResources(ctx context.Context, userID string, roles []string, resources []*common.Origin) error {
+	defer c.decisions.invalidateUser(userID)
+	return c.AuthorizeClient.ApplyRolesForUserOnResources(ctx, userID, roles, resources)
+}
+
+func (c *invalidatingClient[V]) RemoveUserAction(ctx context.Context, userID, action string, resource *common.Origin) error {
+	defer c.decisions.invalidateUser(userID)
+	return c.AuthorizeClient.RemoveUserAction(ctx, userID, action, resource)
+}
+
+func (c *invalidatingClient[V]) AddAction(ctx context.Context, action *authorizeApi.Action) error {
+	defer c.decisions.purge()
+	return c.AuthorizeClient.AddAction(ctx, action)
+}
+
+func (c *invalidatingClient[V]) RemoveAction(ctx context.Context, name string) error {
+	defer c.decisions.purge()
+	return c.AuthorizeClient.RemoveAction(ctx, name)
+}
+
+func (c *invalidatingClient[V]) AddUserRole(ctx context.Context, role *authorizeApi.UserRole) error {
+	defer c.decisions.purge()
+	return c.AuthorizeClient.AddUserRole(ctx, role)
+}
+
+func (c *invalidatingClient[V]) RemoveUserRole(ctx context.Context, roleName string) error {
+	defer c.decisions.purge()
+	return c.AuthorizeClient.RemoveUserRole(ctx, roleName)
+}
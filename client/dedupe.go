@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	authorizeApi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+)
+
+// DeduplicatingClient wraps an AuthorizeClient and collapses identical
+// concurrent read calls into a single call whose result is shared by all
+// callers. The returned slices are shared as well and must not be modified.
+//
+// A caller whose context is done returns right away, the shared call is only
+// cancelled once every caller waiting for it has given up.
+type DeduplicatingClient struct {
+	AuthorizeClient
+
+	flights flightGroup
+}
+
+var _ AuthorizeClient = &DeduplicatingClient{}
+
+func NewDeduplicatingClient(c AuthorizeClient) *DeduplicatingClient {
+	return &DeduplicatingClient{
+		AuthorizeClient: c,
+	}
+}
+
+func (c *DeduplicatingClient) GetResourcesAndActionsByUser(ctx context.Context, userID string) ([]*authorizeApi.ActionResource, error) {
+	return deduplicate(ctx, &c.flights, flightKey("GetResourcesAndActionsByUser", userID), func(ctx context.Context) ([]*authorizeApi.ActionResource, error) {
+		return c.AuthorizeClient.GetResourcesAndActionsByUser(ctx, userID)
+	})
+}
+
+func (c *DeduplicatingClient) GetResourcesAndActionsByUserAndResource(ctx context.Context, userID string, resource *common.Origin) ([]*authorizeApi.ActionResource, error) {
+	return deduplicate(ctx, &c.flights, flightKey("GetResourcesAndActionsByUserAndResource", userID, originString(resource)), func(ctx context.Context) ([]*authorizeApi.ActionResource, error) {
+		return c.AuthorizeClient.GetResourcesAndActionsByUserAndResource(ctx, userID, resource)
+	})
+}
+
+func (c *DeduplicatingClient) GetUserActions(ctx context.Context, userID string) ([]*authorizeApi.Action, error) {
+	return deduplicate(ctx, &c.flights, flightKey("GetUserActions", userID), func(ctx context.Context) ([]*authorizeApi.Action, error) {
+		return c.AuthorizeClient.GetUserActions(ctx, userID)
+	})
+}
+
+func (c *DeduplicatingClient) GetResourcesByUserAction(ctx context.Context, userID, actionName, resourceType string) ([]*common.Origin, error) {
+	return deduplicate(ctx, &c.flights, flightKey("GetResourcesByUserAction", userID, actionName, resourceType), func(ctx context.Context) ([]*common.Origin, error) {
+		return c.AuthorizeClient.GetResourcesByUserAction(ctx, userID, actionName, resourceType)
+	})
+}
+
+func (c *DeduplicatingClient) GetResourcesByType(ctx context.Context, resourceType string) ([]*common.Origin, error) {
+	return deduplicate(ctx, &c.flights, flightKey("GetResourcesByType", resourceType), func(ctx context.Context) ([]*common.Origin, error) {
+		return c.AuthorizeClient.GetResourcesByType(ctx, resourceType)
+	})
+}
+
+func (c *DeduplicatingClient) GetResourcesByOriginAndType(ctx context.Context, resource *common.Origin, resourceType string, depth int32) ([]*common.Origin, error) {
+	return deduplicate(ctx, &c.flights, flightKey("GetResourcesByOriginAndType", originString(resource), resourceType, strconv.Itoa(int(depth))), func(ctx context.Context) ([]*common.Origin, error) {
+		return c.AuthorizeClient.GetResourcesByOriginAndType(ctx, resource, resourceType, depth)
+	})
+}
+
+func (c *DeduplicatingClient) GetResourceParents(ctx context.Context, resource *common.Origin, parentOriginType string) ([]*common.Origin, error) {
+	return deduplicate(ctx, &c.flights, flightKey("GetResourceParents", originString(resource), parentOriginType), func(ctx context.Context) ([]*common.Origin, error) {
+		return c.AuthorizeClient.GetResourceParents(ctx, resource, parentOriginType)
+	})
+}
+
+func (c *DeduplicatingClient) GetResourceChildren(ctx context.Context, resource *common.Origin, childOriginType string) ([]*common.Origin, error) {
+	return deduplicate(ctx, &c.flights, flightKey("GetResourceChildren", originString(resource), childOriginType), func(ctx context.Context) ([]*common.Origin, error) {
+		return c.AuthorizeClient.GetResourceChildren(ctx, resource, childOriginType)
+	})
+}
+
+func (c *DeduplicatingClient) GetUserIDsWithAccessToResource(ctx context.Context, resource *common.Origin) ([]string, error) {
+	return deduplicate(ctx, &c.flights, flightKey("GetUserIDsWithAccessToResource", originString(resource)), func(ctx context.Context) ([]string, error) {
+		return c.AuthorizeClient.GetUserIDsWithAccessToResource(ctx, resource)
+	})
+}
+
+func (c *DeduplicatingClient) GetAllActions(ctx context.Context) ([]*authorizeApi.Action, error) {
+	return deduplicate(ctx, &c.flights, flightKey("GetAllActions"), func(ctx context.Context) ([]*authorizeApi.Action, error) {
+		return c.AuthorizeClient.GetAllActions(ctx)
+	})
+}
+
+func flightKey(method string, args ...string) string {
+	return method + "\x00" + strings.Join(args, "\x00")
+}
+
+func originString(origin *common.Origin) string {
+	return origin.GetId() + "\x01" + origin.GetType() + "\x01" + origin.GetProvider()
+}
+
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	value   any
+	err     error
+}
+
+func deduplicate[T any](ctx context.Context, g *flightGroup, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	value, err := g.do(ctx, key, func(ctx context.Context) (any, error) {
+		return fn(ctx)
+	})
+
+	result, _ := value.(T)
+
+	return result, err
+}
+
+// do calls fn unless there already is a call in flight for key, and waits
+// for the result of the call or for ctx to be done.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (any, error)) (any, error) {
+	g.mu.Lock()
+
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+
+	f, ok := g.flights[key]
+	if !ok {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{
+			done:   make(chan struct{}),
+			cancel: cancel,
+		}
+		g.flights[key] = f
+
+		go func() {
+			defer close(f.done)
+			defer cancel()
+
+			f.value, f.err = fn(flightCtx)
+
+			g.forget(key, f)
+		}()
+	}
+
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+		}
+
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) forget(key string, f *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
@@ -0,0 +1,86 @@
+package client_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	grpcapi "github.com/SKF/proto/v2/authorize"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingClient answers GetUserActions once release is closed and counts
+// the number of calls it receives.
+type blockingClient struct {
+	*authMock.Client
+
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (c *blockingClient) GetUserActions(ctx context.Context, userID string) ([]*grpcapi.Action, error) {
+	c.calls.Add(1)
+
+	select {
+	case <-c.release:
+		return []*grpcapi.Action{{Name: "testAction"}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func Test_DeduplicatingClient_SharesInFlightCalls(t *testing.T) {
+	inner := &blockingClient{Client: authMock.Create(), release: make(chan struct{})}
+	client := authorize.NewDeduplicatingClient(inner)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			actions, err := client.GetUserActions(context.Background(), "testUser")
+			assert.NoError(t, err)
+			assert.Len(t, actions, 1)
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), inner.calls.Load())
+}
+
+func Test_DeduplicatingClient_RespectsCallerCancellation(t *testing.T) {
+	inner := &blockingClient{Client: authMock.Create(), release: make(chan struct{})}
+	client := authorize.NewDeduplicatingClient(inner)
+
+	result := make(chan error)
+
+	go func() {
+		_, err := client.GetUserActions(context.Background(), "testUser")
+		result <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetUserActions(ctx, "testUser")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(inner.release)
+	require.NoError(t, <-result, "The shared call is not cancelled while other callers are waiting")
+
+	assert.Equal(t, int32(1), inner.calls.Load())
+}
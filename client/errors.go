@@ -0,0 +1,155 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/SKF/proto/v2/common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Sentinel errors matched with errors.Is against the errors returned by the
+// client, each one covering one or more gRPC status codes.
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrAlreadyExists    = errors.New("already exists")
+	ErrInvalidArgument  = errors.New("invalid argument")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrUnauthenticated  = errors.New("unauthenticated")
+	ErrUnavailable      = errors.New("unavailable")
+	ErrDeadlineExceeded = errors.New("deadline exceeded")
+	ErrCanceled         = errors.New("canceled")
+	ErrInternal         = errors.New("internal error")
+
+	// ErrResourceExhausted is returned when the service rate limits the
+	// client, which usually calls for a longer backoff than ErrUnavailable.
+	ErrResourceExhausted = errors.New("resource exhausted")
+	// ErrConflict is returned when the call was aborted by a concurrent
+	// change, or the state of the service doesn't allow it, and can succeed
+	// once the caller has re-read the state.
+	ErrConflict = errors.New("conflict")
+	// ErrOutOfRange is returned when the service rejects an argument as past
+	// the valid range, e.g. a page beyond the last one.
+	ErrOutOfRange = errors.New("out of range")
+	// ErrLimitExceeded is returned without calling the service when a request
+	// has more than REQUEST_LENGTH_LIMIT resources. It is never received from
+	// the service, and carries the InvalidArgument status code.
+	ErrLimitExceeded = errors.New("request length limit exceeded")
+)
+
+var codeKinds = map[codes.Code]error{
+	codes.NotFound:           ErrNotFound,
+	codes.AlreadyExists:      ErrAlreadyExists,
+	codes.InvalidArgument:    ErrInvalidArgument,
+	codes.FailedPrecondition: ErrConflict,
+	codes.PermissionDenied:   ErrPermissionDenied,
+	codes.Unauthenticated:    ErrUnauthenticated,
+	codes.Unavailable:        ErrUnavailable,
+	codes.ResourceExhausted:  ErrResourceExhausted,
+	codes.Aborted:            ErrConflict,
+	codes.DeadlineExceeded:   ErrDeadlineExceeded,
+	codes.Canceled:           ErrCanceled,
+	codes.OutOfRange:         ErrOutOfRange,
+}
+
+var kindCodes = map[error]codes.Code{
+	ErrNotFound:          codes.NotFound,
+	ErrAlreadyExists:     codes.AlreadyExists,
+	ErrInvalidArgument:   codes.InvalidArgument,
+	ErrPermissionDenied:  codes.PermissionDenied,
+	ErrUnauthenticated:   codes.Unauthenticated,
+	ErrUnavailable:       codes.Unavailable,
+	ErrResourceExhausted: codes.ResourceExhausted,
+	ErrConflict:          codes.Aborted,
+	ErrDeadlineExceeded:  codes.DeadlineExceeded,
+	ErrCanceled:          codes.Canceled,
+	ErrOutOfRange:        codes.OutOfRange,
+	ErrLimitExceeded:     codes.InvalidArgument,
+	ErrInternal:          codes.Internal,
+}
+
+// Error is the error returned by the client when a call fails. It unwraps to
+// one of the sentinel errors as well as to the original error, and keeps the
+// gRPC status so status.FromError and status.Code keep working.
+type Error struct {
+	// Method is the name of the client method which failed.
+	Method   string
+	UserID   string
+	Action   string
+	Resource *common.Origin
+
+	kind   error
+	status *status.Status
+	cause  error
+}
+
+// NewError creates an Error of the given kind, e.g. ErrNotFound. It can be
+// returned from mock.Client as well as from a mock AuthorizeServer, in which
+// case the client receives an Error of the same kind.
+func NewError(method string, kind error, msg string) *Error {
+	code, ok := kindCodes[kind]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	return &Error{
+		Method: method,
+		kind:   kind,
+		status: status.New(code, msg),
+	}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s", e.Method, e.cause)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Method, e.status.Message())
+}
+
+func (e *Error) Unwrap() []error {
+	if e.cause != nil {
+		return []error{e.kind, e.cause}
+	}
+
+	return []error{e.kind}
+}
+
+func (e *Error) GRPCStatus() *status.Status {
+	return e.status
+}
+
+func (e *Error) Code() codes.Code {
+	return e.status.Code()
+}
+
+// requestIDs identifies what a failed call was about.
+type requestIDs struct {
+	userID   string
+	action   string
+	resource *common.Origin
+}
+
+func wrapError(err error, method string, ids requestIDs) error {
+	if err == nil {
+		return nil
+	}
+
+	st, _ := status.FromError(err)
+
+	kind, ok := codeKinds[st.Code()]
+	if !ok {
+		kind = ErrInternal
+	}
+
+	return &Error{
+		Method:   method,
+		UserID:   ids.userID,
+		Action:   ids.action,
+		Resource: ids.resource,
+		kind:     kind,
+		status:   st,
+		cause:    err,
+	}
+}
@@ -0,0 +1,106 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	grpcapi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_Errors_FromServer(t *testing.T) {
+	server, err := authMock.NewServer()
+	require.NoError(t, err)
+
+	client := clientFor(t, server)
+
+	server.On("IsAuthorized", mock.Anything, mock.Anything).
+		Return((*grpcapi.IsAuthorizedOutput)(nil), authorize.NewError("IsAuthorized", authorize.ErrPermissionDenied, "no access"))
+	server.On("GetResource", mock.Anything, mock.Anything).
+		Return((*grpcapi.GetResourceOutput)(nil), status.Error(codes.NotFound, "resource not found"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resource := &common.Origin{Id: "0", Type: "node", Provider: "1"}
+
+	_, err = client.IsAuthorized(ctx, "testUser", "testAction", resource)
+	require.ErrorIs(t, err, authorize.ErrPermissionDenied)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "The gRPC status is kept")
+
+	var authErr *authorize.Error
+	require.True(t, errors.As(err, &authErr))
+	assert.Equal(t, "IsAuthorized", authErr.Method)
+	assert.Equal(t, "testUser", authErr.UserID)
+	assert.Equal(t, "testAction", authErr.Action)
+	assert.Equal(t, "0", authErr.Resource.GetId())
+
+	_, err = client.GetResource(ctx, "0", "node")
+	require.ErrorIs(t, err, authorize.ErrNotFound)
+	assert.NotErrorIs(t, err, authorize.ErrInternal)
+}
+
+func Test_Errors_FromMockClient(t *testing.T) {
+	client := authMock.Create()
+
+	client.On("RemoveResource", mock.Anything, mock.Anything).
+		Return(authorize.NewError("RemoveResource", authorize.ErrNotFound, "resource not found"))
+
+	err := client.RemoveResource(context.Background(), &common.Origin{Id: "0", Type: "node"})
+	require.ErrorIs(t, err, authorize.ErrNotFound)
+	assert.EqualError(t, err, "RemoveResource: resource not found")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func Test_Errors_LimitExceeded(t *testing.T) {
+	client := authorize.CreateClient()
+
+	err := client.AddResources(context.Background(), generateNodes(authorize.REQUEST_LENGTH_LIMIT+1))
+	require.ErrorIs(t, err, authorize.ErrLimitExceeded)
+	assert.NotErrorIs(t, err, authorize.ErrOutOfRange)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_Errors_Kinds(t *testing.T) {
+	for _, tc := range []struct {
+		code codes.Code
+		kind error
+	}{
+		{code: codes.Unavailable, kind: authorize.ErrUnavailable},
+		{code: codes.ResourceExhausted, kind: authorize.ErrResourceExhausted},
+		{code: codes.Aborted, kind: authorize.ErrConflict},
+		{code: codes.FailedPrecondition, kind: authorize.ErrConflict},
+		{code: codes.InvalidArgument, kind: authorize.ErrInvalidArgument},
+		{code: codes.OutOfRange, kind: authorize.ErrOutOfRange},
+	} {
+		t.Run(tc.code.String(), func(t *testing.T) {
+			server, err := authMock.NewServer()
+			require.NoError(t, err)
+
+			client := clientFor(t, server)
+
+			server.On("GetResource", mock.Anything, mock.Anything).
+				Return((*grpcapi.GetResourceOutput)(nil), status.Error(tc.code, "failure"))
+
+			_, err = client.GetResource(context.Background(), "0", "node")
+			require.ErrorIs(t, err, tc.kind)
+			assert.Equal(t, tc.code, status.Code(err))
+
+			if tc.kind != authorize.ErrUnavailable {
+				assert.NotErrorIs(t, err, authorize.ErrUnavailable)
+			}
+
+			assert.NotErrorIs(t, err, authorize.ErrLimitExceeded)
+		})
+	}
+}
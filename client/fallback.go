@@ -0,0 +1,378 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/audit"
+	"github.com/SKF/go-utility/v2/log"
+	"github.com/SKF/proto/v2/common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const DefaultFallbackMaxStaleness = 15 * time.Minute
+
+// FallbackMode is how a FallbackClient decides when the service can't be
+// reached.
+type FallbackMode uint8
+
+const (
+	// FailClosed denies the action.
+	FailClosed FallbackMode = iota
+	// FailOpen allows the action, meant for low-risk read actions.
+	FailOpen
+	// LastKnown serves the last decision returned by the service, if it isn't
+	// older than the max staleness, and denies the action otherwise.
+	LastKnown
+)
+
+func (m FallbackMode) String() string {
+	switch m {
+	case FailClosed:
+		return "fail_closed"
+	case FailOpen:
+		return "fail_open"
+	case LastKnown:
+		return "last_known"
+	}
+
+	return "unknown"
+}
+
+// FallbackConfig configures the policy of a FallbackClient.
+type FallbackConfig struct {
+	// Default is the mode of the actions missing from Actions, as well as of
+	// IsAuthorizedByEndpoint. Defaults to FailClosed.
+	Default FallbackMode
+	// Actions overrides the mode of individual actions.
+	Actions map[string]FallbackMode
+	// MaxStaleness is how old a decision served by LastKnown may be. Defaults
+	// to DefaultFallbackMaxStaleness.
+	MaxStaleness time.Duration
+	// MaxEntries bounds the number of decisions kept for LastKnown, the least
+	// recently used decision is evicted first. Defaults to
+	// DefaultCacheMaxEntries.
+	MaxEntries int
+	// Audit, if not nil, receives a record of every fallback decision, with
+	// the mode set as audit.Record.Fallback. The failed call itself is
+	// recorded by the client given WithAudit, if any.
+	Audit audit.Sink
+	// AuditCaller returns the caller metadata of the audit records.
+	AuditCaller audit.CallerFunc
+	// AuditLogger logs the errors returned by Audit. Defaults to log.Base().
+	AuditLogger log.Logger
+}
+
+// FallbackDecision is a decision made by a FallbackClient.
+type FallbackDecision struct {
+	Allowed bool
+	Reason  string
+	// Fallback is set if the service couldn't be reached, in which case Mode
+	// is the mode the decision was made by and Err the error of the call.
+	Fallback bool
+	Mode     FallbackMode
+	Err      error
+	// Age is how old a decision served by LastKnown is.
+	Age time.Duration
+}
+
+// FallbackError is returned by FallbackClient, along with the decisions made
+// by the policy, when the service can't be reached. It unwraps to the error of
+// the call, so errors.Is keeps matching e.g. ErrUnavailable, while callers
+// acting on the decisions tell them apart with errors.As.
+type FallbackError struct {
+	Mode FallbackMode
+	Err  error
+	// Decisions are the decisions returned with the error, one per resource
+	// for IsAuthorizedBulk.
+	Decisions []FallbackDecision
+}
+
+func (e *FallbackError) Error() string {
+	return fmt.Sprintf("%s fallback: %s", e.Mode, e.Err)
+}
+
+func (e *FallbackError) Unwrap() error {
+	return e.Err
+}
+
+type lastKnownDecision struct {
+	ok        bool
+	decidedAt time.Time
+}
+
+// FallbackClient wraps an AuthorizeClient and decides according to a policy
+// per action when IsAuthorized, IsAuthorizedWithReason, IsAuthorizedBulk or
+// IsAuthorizedByEndpoint fail as the service is unavailable or doesn't answer
+// in time, unless the context of the call is done. Such decisions are
+// returned along with a *FallbackError, and are written to the audit sink of
+// the config, marked with the mode. Other errors are returned as is. Decide
+// returns the decisions of the policy without an error, marked by
+// FallbackDecision.Fallback instead.
+//
+// Writes made through the same FallbackClient drop the affected last known
+// decisions, as CachingClient does.
+type FallbackClient struct {
+	invalidatingClient[lastKnownDecision]
+
+	config FallbackConfig
+}
+
+var _ AuthorizeClient = &FallbackClient{}
+
+func NewFallbackClient(c AuthorizeClient, config FallbackConfig) *FallbackClient {
+	if config.MaxStaleness <= 0 {
+		config.MaxStaleness = DefaultFallbackMaxStaleness
+	}
+
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultCacheMaxEntries
+	}
+
+	return &FallbackClient{
+		invalidatingClient: invalidatingClient[lastKnownDecision]{
+			AuthorizeClient: c,
+			decisions:       newDecisionStore[lastKnownDecision](config.MaxEntries),
+		},
+		config: config,
+	}
+}
+
+// Decide is IsAuthorizedWithReason, reporting whether the decision was made
+// by the fallback policy by FallbackDecision.Fallback rather than by an error.
+func (c *FallbackClient) Decide(ctx context.Context, userID, action string, resource *common.Origin) (FallbackDecision, error) {
+	key := decisionKey{
+		kind:     decisionIsAuthorized,
+		userID:   userID,
+		action:   action,
+		resource: keyOf(resource),
+	}
+	mode := c.mode(action)
+	generation := c.decisions.current()
+
+	ok, reason, err := c.AuthorizeClient.IsAuthorizedWithReason(ctx, userID, action, resource)
+	if err == nil {
+		c.remember(mode, key, generation, ok)
+		return FallbackDecision{Allowed: ok, Reason: reason}, nil
+	}
+
+	if !isUnreachable(ctx, err) {
+		return FallbackDecision{Allowed: ok, Reason: reason}, err
+	}
+
+	d := c.fallback(mode, key, err)
+	c.audit(ctx, audit.Record{
+		Method:   "IsAuthorizedWithReason",
+		UserID:   userID,
+		Action:   action,
+		Resource: auditResource(resource),
+	}, d)
+
+	return d, nil
+}
+
+func (c *FallbackClient) IsAuthorized(ctx context.Context, userID, action string, resource *common.Origin) (bool, error) {
+	key := decisionKey{
+		kind:     decisionIsAuthorized,
+		userID:   userID,
+		action:   action,
+		resource: keyOf(resource),
+	}
+	mode := c.mode(action)
+	generation := c.decisions.current()
+
+	ok, err := c.AuthorizeClient.IsAuthorized(ctx, userID, action, resource)
+	if err == nil {
+		c.remember(mode, key, generation, ok)
+		return ok, nil
+	}
+
+	if !isUnreachable(ctx, err) {
+		return false, err
+	}
+
+	d := c.fallback(mode, key, err)
+	c.audit(ctx, audit.Record{
+		Method:   "IsAuthorized",
+		UserID:   userID,
+		Action:   action,
+		Resource: auditResource(resource),
+	}, d)
+
+	return d.Allowed, &FallbackError{Mode: mode, Err: err, Decisions: []FallbackDecision{d}}
+}
+
+func (c *FallbackClient) IsAuthorizedWithReason(ctx context.Context, userID, action string, resource *common.Origin) (bool, string, error) {
+	d, err := c.Decide(ctx, userID, action, resource)
+	if err == nil && d.Fallback {
+		err = &FallbackError{Mode: d.Mode, Err: d.Err, Decisions: []FallbackDecision{d}}
+	}
+
+	return d.Allowed, d.Reason, err
+}
+
+func (c *FallbackClient) IsAuthorizedBulk(ctx context.Context, userID, action string, resourcesInput []*common.Origin) ([]*common.Origin, []bool, error) {
+	mode := c.mode(action)
+	generation := c.decisions.current()
+
+	resources, oks, err := c.AuthorizeClient.IsAuthorizedBulk(ctx, userID, action, resourcesInput)
+	if err == nil {
+		// Responses without a decision, e.g. from a misbehaving server, are
+		// not remembered.
+		for i := range min(len(resources), len(oks)) {
+			key := decisionKey{kind: decisionIsAuthorized, userID: userID, action: action, resource: keyOf(resources[i])}
+			c.remember(mode, key, generation, oks[i])
+		}
+
+		return resources, oks, nil
+	}
+
+	if !isUnreachable(ctx, err) {
+		return resources, oks, err
+	}
+
+	resources = make([]*common.Origin, len(resourcesInput))
+	oks = make([]bool, len(resourcesInput))
+	fallbackErr := &FallbackError{Mode: mode, Err: err, Decisions: make([]FallbackDecision, len(resourcesInput))}
+
+	for i, resource := range resourcesInput {
+		key := decisionKey{kind: decisionIsAuthorized, userID: userID, action: action, resource: keyOf(resource)}
+
+		d := c.fallback(mode, key, err)
+		c.audit(ctx, audit.Record{
+			Method:   "IsAuthorizedBulk",
+			UserID:   userID,
+			Action:   action,
+			Resource: auditResource(resource),
+		}, d)
+
+		resources[i] = resource
+		oks[i] = d.Allowed
+		fallbackErr.Decisions[i] = d
+	}
+
+	return resources, oks, fallbackErr
+}
+
+// IsAuthorizedByEndpoint decides according to the Default mode of the config
+// when the service can't be reached.
+func (c *FallbackClient) IsAuthorizedByEndpoint(ctx context.Context, api, method, endpoint, userID string) (bool, error) {
+	key := decisionKey{
+		kind:     decisionByEndpoint,
+		userID:   userID,
+		api:      api,
+		method:   method,
+		endpoint: endpoint,
+	}
+	mode := c.config.Default
+	generation := c.decisions.current()
+
+	ok, err := c.AuthorizeClient.IsAuthorizedByEndpoint(ctx, api, method, endpoint, userID)
+	if err == nil {
+		c.remember(mode, key, generation, ok)
+		return ok, nil
+	}
+
+	if !isUnreachable(ctx, err) {
+		return false, err
+	}
+
+	d := c.fallback(mode, key, err)
+	c.audit(ctx, audit.Record{
+		Method:   "IsAuthorizedByEndpoint",
+		UserID:   userID,
+		Endpoint: strings.Join([]string{api, method, endpoint}, " "),
+	}, d)
+
+	return d.Allowed, &FallbackError{Mode: mode, Err: err, Decisions: []FallbackDecision{d}}
+}
+
+// Purge drops every last known decision.
+func (c *FallbackClient) Purge() {
+	c.decisions.purge()
+}
+
+func (c *FallbackClient) mode(action string) FallbackMode {
+	if mode, ok := c.config.Actions[action]; ok {
+		return mode
+	}
+
+	return c.config.Default
+}
+
+// remember keeps the decision for LastKnown unless the decisions were
+// invalidated while it was being fetched.
+func (c *FallbackClient) remember(mode FallbackMode, key decisionKey, generation uint64, ok bool) {
+	if mode != LastKnown {
+		return
+	}
+
+	c.decisions.add(key, generation, lastKnownDecision{ok: ok, decidedAt: time.Now()})
+}
+
+// fallback decides according to mode after the call failed with err.
+func (c *FallbackClient) fallback(mode FallbackMode, key decisionKey, err error) FallbackDecision {
+	d := FallbackDecision{
+		Reason:   ReasonFallbackFailClosed,
+		Fallback: true,
+		Mode:     mode,
+		Err:      err,
+	}
+
+	switch mode {
+	case FailOpen:
+		d.Allowed = true
+		d.Reason = ReasonFallbackFailOpen
+	case LastKnown:
+		now := time.Now()
+		known, _, ok := c.decisions.lookup(key, func(known lastKnownDecision) bool {
+			return now.Sub(known.decidedAt) <= c.config.MaxStaleness
+		})
+		if !ok {
+			return d
+		}
+
+		d.Allowed = known.ok
+		d.Reason = ReasonFallbackLastKnown
+		d.Age = now.Sub(known.decidedAt)
+	}
+
+	return d
+}
+
+func (c *FallbackClient) audit(ctx context.Context, record audit.Record, d FallbackDecision) {
+	if c.config.Audit == nil {
+		return
+	}
+
+	record.Decision = auditDecision(d.Allowed)
+	record.Reason = d.Reason
+	record.Fallback = d.Mode.String()
+	record.Error = d.Err.Error()
+
+	writeAudit(ctx, c.config.AuditLogger, c.config.Audit, c.config.AuditCaller, record)
+}
+
+// isUnreachable reports whether err means that the service couldn't be
+// reached or didn't answer in time. A call whose context is done failed
+// because of the caller rather than the service, so it never falls back.
+func isUnreachable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if errors.Is(err, ErrUnavailable) || errors.Is(err, ErrDeadlineExceeded) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,228 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/client/audit"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fallbackNode = &common.Origin{Id: "0", Type: "node", Provider: "1"}
+	errDown      = authorize.NewError("IsAuthorized", authorize.ErrUnavailable, "connection refused")
+)
+
+// requireFallback asserts that err marks a decision of the policy with mode.
+func requireFallback(t *testing.T, err error, mode authorize.FallbackMode) *authorize.FallbackError {
+	t.Helper()
+
+	var fallbackErr *authorize.FallbackError
+	require.ErrorAs(t, err, &fallbackErr)
+	assert.Equal(t, mode, fallbackErr.Mode)
+	assert.ErrorIs(t, err, authorize.ErrUnavailable, "The error of the call is kept")
+
+	return fallbackErr
+}
+
+func Test_FallbackClient_FailClosedByDefault(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "write", fallbackNode).Return(false, errDown)
+
+	sink := &recordingSink{}
+	client := authorize.NewFallbackClient(inner, authorize.FallbackConfig{
+		Actions: map[string]authorize.FallbackMode{"read": authorize.FailOpen},
+		Audit:   sink,
+	})
+
+	ok, err := client.IsAuthorized(context.Background(), "testUser", "write", fallbackNode)
+	fallbackErr := requireFallback(t, err, authorize.FailClosed)
+	assert.False(t, ok)
+	require.Len(t, fallbackErr.Decisions, 1)
+	assert.Equal(t, authorize.ReasonFallbackFailClosed, fallbackErr.Decisions[0].Reason)
+
+	records := sink.take()
+	require.Len(t, records, 1)
+	assert.Equal(t, "IsAuthorized", records[0].Method)
+	assert.Equal(t, audit.DecisionDeny, records[0].Decision)
+	assert.Equal(t, authorize.ReasonFallbackFailClosed, records[0].Reason)
+	assert.Equal(t, "fail_closed", records[0].Fallback)
+	assert.Equal(t, errDown.Error(), records[0].Error)
+}
+
+func Test_FallbackClient_FailOpen(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorizedWithReason", mock.Anything, "testUser", "read", fallbackNode).Return(false, "", errDown)
+
+	sink := &recordingSink{}
+	client := authorize.NewFallbackClient(inner, authorize.FallbackConfig{
+		Actions: map[string]authorize.FallbackMode{"read": authorize.FailOpen},
+		Audit:   sink,
+	})
+
+	d, err := client.Decide(context.Background(), "testUser", "read", fallbackNode)
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.True(t, d.Fallback)
+	assert.Equal(t, authorize.FailOpen, d.Mode)
+	assert.Equal(t, authorize.ReasonFallbackFailOpen, d.Reason)
+	assert.ErrorIs(t, d.Err, authorize.ErrUnavailable)
+
+	ok, reason, err := client.IsAuthorizedWithReason(context.Background(), "testUser", "read", fallbackNode)
+	requireFallback(t, err, authorize.FailOpen)
+	assert.True(t, ok)
+	assert.Equal(t, authorize.ReasonFallbackFailOpen, reason)
+
+	records := sink.take()
+	require.Len(t, records, 2)
+	assert.Equal(t, audit.DecisionAllow, records[0].Decision)
+	assert.Equal(t, "fail_open", records[0].Fallback)
+}
+
+func Test_FallbackClient_LastKnown(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "read", fallbackNode).Return(true, nil).Once()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "read", fallbackNode).Return(false, errDown)
+
+	client := authorize.NewFallbackClient(inner, authorize.FallbackConfig{
+		Default:      authorize.LastKnown,
+		MaxStaleness: 50 * time.Millisecond,
+	})
+
+	ok, err := client.IsAuthorized(context.Background(), "testUser", "read", fallbackNode)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = client.IsAuthorized(context.Background(), "testUser", "read", fallbackNode)
+	fallbackErr := requireFallback(t, err, authorize.LastKnown)
+	assert.True(t, ok, "The last known decision is served")
+	assert.Equal(t, authorize.ReasonFallbackLastKnown, fallbackErr.Decisions[0].Reason)
+
+	time.Sleep(60 * time.Millisecond)
+
+	ok, err = client.IsAuthorized(context.Background(), "testUser", "read", fallbackNode)
+	requireFallback(t, err, authorize.LastKnown)
+	assert.False(t, ok, "Stale decisions fail closed")
+
+	inner.AssertExpectations(t)
+}
+
+func Test_FallbackClient_LastKnownInvalidatedByWrites(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorizedWithReason", mock.Anything, "testUser", "read", fallbackNode).Return(true, "", nil).Once()
+	inner.On("IsAuthorizedWithReason", mock.Anything, "testUser", "read", fallbackNode).Return(false, "", errDown)
+	inner.On("RemoveUserAction", mock.Anything, "testUser", "read", fallbackNode).Return(nil)
+
+	client := authorize.NewFallbackClient(inner, authorize.FallbackConfig{Default: authorize.LastKnown})
+
+	d, err := client.Decide(context.Background(), "testUser", "read", fallbackNode)
+	require.NoError(t, err)
+	require.True(t, d.Allowed)
+	assert.False(t, d.Fallback)
+
+	require.NoError(t, client.RemoveUserAction(context.Background(), "testUser", "read", fallbackNode))
+
+	d, err = client.Decide(context.Background(), "testUser", "read", fallbackNode)
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, authorize.ReasonFallbackFailClosed, d.Reason)
+}
+
+func Test_FallbackClient_Bulk(t *testing.T) {
+	other := &common.Origin{Id: "1", Type: "node", Provider: "1"}
+	resources := []*common.Origin{fallbackNode, other}
+
+	inner := authMock.Create()
+	inner.On("IsAuthorizedBulk", "testUser", "read", resources).Return([]*common.Origin{fallbackNode, other}, []bool{true, false}, nil).Once()
+	inner.On("IsAuthorizedBulk", "testUser", "read", resources).Return([]*common.Origin(nil), []bool(nil), errDown)
+
+	sink := &recordingSink{}
+	client := authorize.NewFallbackClient(inner, authorize.FallbackConfig{
+		Actions: map[string]authorize.FallbackMode{"read": authorize.LastKnown},
+		Audit:   sink,
+	})
+
+	_, _, err := client.IsAuthorizedBulk(context.Background(), "testUser", "read", resources)
+	require.NoError(t, err)
+
+	gotResources, oks, err := client.IsAuthorizedBulk(context.Background(), "testUser", "read", resources)
+	fallbackErr := requireFallback(t, err, authorize.LastKnown)
+	assert.Equal(t, resources, gotResources)
+	assert.Equal(t, []bool{true, false}, oks)
+	require.Len(t, fallbackErr.Decisions, 2, "Every item has a decision")
+	assert.True(t, fallbackErr.Decisions[0].Allowed)
+	assert.False(t, fallbackErr.Decisions[1].Allowed)
+
+	records := sink.take()
+	require.Len(t, records, 2, "Every item is recorded")
+	for _, record := range records {
+		assert.Equal(t, "last_known", record.Fallback)
+		assert.Equal(t, authorize.ReasonFallbackLastKnown, record.Reason)
+	}
+}
+
+func Test_FallbackClient_OtherErrors(t *testing.T) {
+	denied := authorize.NewError("IsAuthorized", authorize.ErrPermissionDenied, "denied")
+
+	inner := authMock.Create()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "read", fallbackNode).Return(false, denied)
+
+	client := authorize.NewFallbackClient(inner, authorize.FallbackConfig{Default: authorize.FailOpen})
+
+	ok, err := client.IsAuthorized(context.Background(), "testUser", "read", fallbackNode)
+	assert.ErrorIs(t, err, authorize.ErrPermissionDenied)
+	assert.False(t, ok, "Only unreachable services fall back")
+}
+
+func Test_FallbackClient_BulkMismatchedResponses(t *testing.T) {
+	other := &common.Origin{Id: "1", Type: "node", Provider: "1"}
+	resources := []*common.Origin{fallbackNode, other}
+
+	inner := authMock.Create()
+	inner.On("IsAuthorizedBulk", "testUser", "read", resources).Return(resources, []bool{true}, nil)
+
+	client := authorize.NewFallbackClient(inner, authorize.FallbackConfig{Default: authorize.LastKnown})
+
+	gotResources, oks, err := client.IsAuthorizedBulk(context.Background(), "testUser", "read", resources)
+	require.NoError(t, err)
+	assert.Equal(t, resources, gotResources)
+	assert.Equal(t, []bool{true}, oks, "The response is returned as is")
+}
+
+func Test_FallbackClient_ByEndpoint(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorizedByEndpoint", mock.Anything, "api", "GET", "/nodes", "testUser").Return(true, nil).Once()
+	inner.On("IsAuthorizedByEndpoint", mock.Anything, "api", "GET", "/nodes", "testUser").Return(false, errDown)
+
+	client := authorize.NewFallbackClient(inner, authorize.FallbackConfig{Default: authorize.LastKnown})
+
+	ok, err := client.IsAuthorizedByEndpoint(context.Background(), "api", "GET", "/nodes", "testUser")
+	require.NoError(t, err, "Decisions of the service are returned without an error")
+	assert.True(t, ok)
+
+	ok, err = client.IsAuthorizedByEndpoint(context.Background(), "api", "GET", "/nodes", "testUser")
+	requireFallback(t, err, authorize.LastKnown)
+	assert.True(t, ok)
+}
+
+func Test_FallbackClient_CallerContextDone(t *testing.T) {
+	inner := authMock.Create()
+	inner.On("IsAuthorized", mock.Anything, "testUser", "read", fallbackNode).Return(false, errDown)
+
+	client := authorize.NewFallbackClient(inner, authorize.FallbackConfig{Default: authorize.FailOpen})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ok, err := client.IsAuthorized(ctx, "testUser", "read", fallbackNode)
+	require.ErrorIs(t, err, authorize.ErrUnavailable)
+	assert.NotErrorAs(t, err, new(*authorize.FallbackError), "Calls given up by the caller don't fall back")
+	assert.False(t, ok)
+}
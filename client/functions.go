@@ -12,15 +12,15 @@ import (
 
 const REQUEST_LENGTH_LIMIT = 1000
 
-func requestLengthLimit(requestLength int) error {
+func requestLengthLimit(method string, requestLength int) error {
 	if requestLength > REQUEST_LENGTH_LIMIT {
-		return fmt.Errorf("request length limit exceeded. max: %d actual: %d", REQUEST_LENGTH_LIMIT, requestLength)
+		return NewError(method, ErrLimitExceeded, fmt.Sprintf("request length limit exceeded. max: %d actual: %d", REQUEST_LENGTH_LIMIT, requestLength))
 	}
 	return nil
 }
 
 func (c *client) SetRequestTimeout(d time.Duration) {
-	c.requestTimeout = d
+	c.requestTimeout.Store(int64(d))
 }
 
 func (c *client) IsAuthorized(ctx context.Context, userID, action string, resource *common.Origin) (bool, error) {
@@ -30,14 +30,14 @@ func (c *client) IsAuthorized(ctx context.Context, userID, action string, resour
 		Resource: resource,
 	})
 	if err != nil {
-		return false, err
+		return false, wrapError(err, "IsAuthorized", requestIDs{userID: userID, action: action, resource: resource})
 	}
 
 	return result.Ok, err
 }
 
 func (c *client) IsAuthorizedBulk(ctx context.Context, userID, action string, resourcesInput []*common.Origin) ([]*common.Origin, []bool, error) {
-	if err := requestLengthLimit(len(resourcesInput)); err != nil {
+	if err := requestLengthLimit("IsAuthorizedBulk", len(resourcesInput)); err != nil {
 		return nil, nil, err
 	}
 
@@ -47,7 +47,7 @@ func (c *client) IsAuthorizedBulk(ctx context.Context, userID, action string, re
 		Resources: resourcesInput,
 	})
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, wrapError(err, "IsAuthorizedBulk", requestIDs{userID: userID, action: action})
 	}
 
 	responses := results.GetResponses()
@@ -79,7 +79,7 @@ func (c *client) IsAuthorizedByEndpoint(ctx context.Context, api, method, endpoi
 		UserId:   userID,
 	})
 	if err != nil {
-		return false, err
+		return false, wrapError(err, "IsAuthorizedByEndpoint", requestIDs{userID: userID})
 	}
 
 	return result.Ok, nil
@@ -89,7 +89,7 @@ func (c *client) AddResource(ctx context.Context, resource *common.Origin) error
 	_, err := c.api.AddResource(ctx, &authorizeApi.AddResourceInput{
 		Resource: resource,
 	})
-	return err
+	return wrapError(err, "AddResource", requestIDs{resource: resource})
 }
 
 func (c *client) GetResource(ctx context.Context, id, originType string) (*common.Origin, error) {
@@ -99,39 +99,39 @@ func (c *client) GetResource(ctx context.Context, id, originType string) (*commo
 	}
 	resource, err := c.api.GetResource(ctx, &input)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err, "GetResource", requestIDs{resource: &common.Origin{Id: id, Type: originType}})
 	}
 
 	return resource.Resource, err
 }
 
 func (c *client) AddResources(ctx context.Context, resources []*common.Origin) error {
-	if err := requestLengthLimit(len(resources)); err != nil {
+	if err := requestLengthLimit("AddResources", len(resources)); err != nil {
 		return err
 	}
 
 	_, err := c.api.AddResources(ctx, &authorizeApi.AddResourcesInput{
 		Resource: resources,
 	})
-	return err
+	return wrapError(err, "AddResources", requestIDs{})
 }
 
 func (c *client) RemoveResource(ctx context.Context, resource *common.Origin) error {
 	_, err := c.api.RemoveResource(ctx, &authorizeApi.RemoveResourceInput{
 		Resource: resource,
 	})
-	return err
+	return wrapError(err, "RemoveResource", requestIDs{resource: resource})
 }
 
 func (c *client) RemoveResources(ctx context.Context, resources []*common.Origin) error {
-	if err := requestLengthLimit(len(resources)); err != nil {
+	if err := requestLengthLimit("RemoveResources", len(resources)); err != nil {
 		return err
 	}
 
 	_, err := c.api.RemoveResources(ctx, &authorizeApi.RemoveResourcesInput{
 		Resource: resources,
 	})
-	return err
+	return wrapError(err, "RemoveResources", requestIDs{})
 }
 
 func (c *client) GetResourcesByUserAction(ctx context.Context, userID, actionName, resourceType string) (resources []*common.Origin, err error) {
@@ -142,6 +142,7 @@ func (c *client) GetResourcesByUserAction(ctx context.Context, userID, actionNam
 	}
 	output, err := c.api.GetResourcesByUserAction(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetResourcesByUserAction", requestIDs{userID: userID, action: actionName})
 		return
 	}
 	if output != nil {
@@ -158,6 +159,7 @@ func (c *client) GetResourcesWithActionsAccess(ctx context.Context, actions []st
 	}
 	output, err := c.api.GetResourcesWithActionsAccess(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetResourcesWithActionsAccess", requestIDs{resource: resource})
 		return
 	}
 	if output != nil {
@@ -170,6 +172,7 @@ func (c *client) GetResourcesByType(ctx context.Context, resourceType string) (r
 	input := authorizeApi.GetResourcesByTypeInput{ResourceType: resourceType}
 	output, err := c.api.GetResourcesByType(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetResourcesByType", requestIDs{})
 		return
 	}
 	if output != nil {
@@ -183,16 +186,16 @@ func (c *client) AddResourceRelation(ctx context.Context, resource, parent *comm
 		Resource: resource,
 		Parent:   parent,
 	})
-	return err
+	return wrapError(err, "AddResourceRelation", requestIDs{resource: resource})
 }
 
 func (c *client) AddResourceRelations(ctx context.Context, resources *authorizeApi.AddResourceRelationsInput) error {
-	if err := requestLengthLimit(len(resources.Relation)); err != nil {
+	if err := requestLengthLimit("AddResourceRelations", len(resources.Relation)); err != nil {
 		return err
 	}
 
 	_, err := c.api.AddResourceRelations(ctx, resources)
-	return err
+	return wrapError(err, "AddResourceRelations", requestIDs{})
 }
 
 func (c *client) RemoveResourceRelation(ctx context.Context, resource, parent *common.Origin) error {
@@ -200,16 +203,16 @@ func (c *client) RemoveResourceRelation(ctx context.Context, resource, parent *c
 		Resource: resource,
 		Parent:   parent,
 	})
-	return err
+	return wrapError(err, "RemoveResourceRelation", requestIDs{resource: resource})
 }
 
 func (c *client) RemoveResourceRelations(ctx context.Context, resources *authorizeApi.RemoveResourceRelationsInput) error {
-	if err := requestLengthLimit(len(resources.Relation)); err != nil {
+	if err := requestLengthLimit("RemoveResourceRelations", len(resources.Relation)); err != nil {
 		return err
 	}
 
 	_, err := c.api.RemoveResourceRelations(ctx, resources)
-	return err
+	return wrapError(err, "RemoveResourceRelations", requestIDs{})
 }
 
 func (c *client) ApplyUserAction(ctx context.Context, userID, action string, resource *common.Origin) error {
@@ -218,7 +221,7 @@ func (c *client) ApplyUserAction(ctx context.Context, userID, action string, res
 		Action:   action,
 		Resource: resource,
 	})
-	return err
+	return wrapError(err, "ApplyUserAction", requestIDs{userID: userID, action: action, resource: resource})
 }
 
 func (c *client) ApplyRolesForUserOnResources(ctx context.Context, userID string, roles []string, resources []*common.Origin) error {
@@ -228,7 +231,7 @@ func (c *client) ApplyRolesForUserOnResources(ctx context.Context, userID string
 		Resources: resources,
 	})
 
-	return err
+	return wrapError(err, "ApplyRolesForUserOnResources", requestIDs{userID: userID})
 }
 
 func (c *client) RemoveUserAction(ctx context.Context, userID, action string, resource *common.Origin) error {
@@ -237,13 +240,14 @@ func (c *client) RemoveUserAction(ctx context.Context, userID, action string, re
 		Action:   action,
 		Resource: resource,
 	})
-	return err
+	return wrapError(err, "RemoveUserAction", requestIDs{userID: userID, action: action, resource: resource})
 }
 
 func (c *client) GetResourcesByOriginAndType(ctx context.Context, resource *common.Origin, resourceType string, depth int32) (resources []*common.Origin, err error) {
 	input := authorizeApi.GetResourcesByOriginAndTypeInput{ResourceType: resourceType, Resource: resource, Depth: depth}
 	output, err := c.api.GetResourcesByOriginAndType(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetResourcesByOriginAndType", requestIDs{resource: resource})
 		return
 	}
 	if output != nil {
@@ -256,6 +260,7 @@ func (c *client) GetResourceParents(ctx context.Context, resource *common.Origin
 	input := authorizeApi.GetResourceParentsInput{ParentOriginType: parentOriginType, Resource: resource}
 	output, err := c.api.GetResourceParents(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetResourceParents", requestIDs{resource: resource})
 		return
 	}
 	if output != nil {
@@ -268,6 +273,7 @@ func (c *client) GetResourceChildren(ctx context.Context, resource *common.Origi
 	input := authorizeApi.GetResourceChildrenInput{ChildOriginType: childOriginType, Resource: resource}
 	output, err := c.api.GetResourceChildren(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetResourceChildren", requestIDs{resource: resource})
 		return
 	}
 	if output != nil {
@@ -280,6 +286,7 @@ func (c *client) GetUserIDsWithAccessToResource(ctx context.Context, resource *c
 	input := authorizeApi.GetUserIDsWithAccessToResourceInput{Resource: resource}
 	output, err := c.api.GetUserIDsWithAccessToResource(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetUserIDsWithAccessToResource", requestIDs{resource: resource})
 		return
 	}
 	if output != nil {
@@ -292,6 +299,7 @@ func (c *client) GetActionsByUserRole(ctx context.Context, userRole string) (act
 	input := authorizeApi.GetActionsByUserRoleInput{UserRole: userRole}
 	output, err := c.api.GetActionsByUserRole(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetActionsByUserRole", requestIDs{})
 		return
 	}
 
@@ -306,6 +314,7 @@ func (c *client) GetResourcesAndActionsByUser(ctx context.Context, userID string
 	input := authorizeApi.GetResourcesAndActionsByUserInput{UserId: userID}
 	output, err := c.api.GetResourcesAndActionsByUser(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetResourcesAndActionsByUser", requestIDs{userID: userID})
 		return
 	}
 
@@ -320,6 +329,7 @@ func (c *client) GetResourcesAndActionsByUserAndResource(ctx context.Context, us
 	input := authorizeApi.GetResourcesAndActionsByUserAndResourceInput{UserId: userID, Resource: resource}
 	output, err := c.api.GetResourcesAndActionsByUserAndResource(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetResourcesAndActionsByUserAndResource", requestIDs{userID: userID, resource: resource})
 		return
 	}
 
@@ -332,18 +342,19 @@ func (c *client) GetResourcesAndActionsByUserAndResource(ctx context.Context, us
 
 func (c *client) AddAction(ctx context.Context, action *authorizeApi.Action) error {
 	_, err := c.api.AddAction(ctx, &authorizeApi.AddActionInput{Action: action})
-	return err
+	return wrapError(err, "AddAction", requestIDs{action: action.GetName()})
 }
 
 func (c *client) RemoveAction(ctx context.Context, name string) error {
 	_, err := c.api.RemoveAction(ctx, &authorizeApi.RemoveActionInput{Name: name})
-	return err
+	return wrapError(err, "RemoveAction", requestIDs{action: name})
 }
 
 func (c *client) GetAction(ctx context.Context, name string) (actions *authorizeApi.Action, err error) {
 	input := authorizeApi.GetActionInput{Name: name}
 	action, err := c.api.GetAction(ctx, &input)
 	if err != nil {
+		err = wrapError(err, "GetAction", requestIDs{action: name})
 		return
 	}
 	return action.Action, err
@@ -352,6 +363,7 @@ func (c *client) GetAction(ctx context.Context, name string) (actions *authorize
 func (c *client) GetAllActions(ctx context.Context) (actions []*authorizeApi.Action, err error) {
 	allActions, err := c.api.GetAllActions(ctx, &common.Void{})
 	if err != nil {
+		err = wrapError(err, "GetAllActions", requestIDs{})
 		return
 	}
 	if allActions != nil {
@@ -367,6 +379,7 @@ func (c *client) GetUserActions(ctx context.Context, userID string) (actions []*
 	})
 
 	if err != nil {
+		err = wrapError(err, "GetUserActions", requestIDs{userID: userID})
 		return
 	} else if result != nil {
 		actions = result.Actions
@@ -377,7 +390,7 @@ func (c *client) GetUserActions(ctx context.Context, userID string) (actions []*
 
 func (c *client) AddUserRole(ctx context.Context, role *authorizeApi.UserRole) error {
 	_, err := c.api.AddUserRole(ctx, role)
-	return err
+	return wrapError(err, "AddUserRole", requestIDs{})
 }
 
 func (c *client) GetUserRole(ctx context.Context, roleName string) (role *authorizeApi.UserRole, err error) {
@@ -385,6 +398,7 @@ func (c *client) GetUserRole(ctx context.Context, roleName string) (role *author
 		RoleName: roleName,
 	})
 	if err != nil {
+		err = wrapError(err, "GetUserRole", requestIDs{})
 		return
 	}
 
@@ -401,7 +415,7 @@ func (c *client) RemoveUserRole(ctx context.Context, roleName string) error {
 		RoleName: roleName,
 	})
 
-	return err
+	return wrapError(err, "RemoveUserRole", requestIDs{})
 }
 
 func (c *client) IsAuthorizedWithReason(ctx context.Context, userID, action string, resource *common.Origin) (bool, string, error) {
@@ -411,7 +425,7 @@ func (c *client) IsAuthorizedWithReason(ctx context.Context, userID, action stri
 		Resource: resource,
 	})
 	if err != nil || result == nil {
-		return false, "error occurred ", err
+		return false, "error occurred ", wrapError(err, "IsAuthorizedWithReason", requestIDs{userID: userID, action: action, resource: resource})
 	}
 
 	return result.Ok, result.Reason, err
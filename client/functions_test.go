@@ -2,6 +2,7 @@ package client_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -189,3 +190,29 @@ func Test_IsAuthorizedWithReason(t *testing.T) {
 
 	server.AssertExpectations(t)
 }
+
+func Test_SetRequestTimeout_Concurrently(t *testing.T) {
+	server, err := authMock.NewServer()
+	require.NoError(t, err)
+
+	client := clientFor(t, server)
+
+	server.On("DeepPing", mock.Anything, mock.Anything).Return(&common.PrimitiveString{Value: ""}, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			client.SetRequestTimeout(time.Duration(i+1) * time.Second)
+		}()
+
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, client.DeepPing(context.Background()))
+		}()
+	}
+
+	wg.Wait()
+}
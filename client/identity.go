@@ -0,0 +1,142 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrServerIdentityMismatch is returned by handshakes with a server whose
+// certificate chains to the CA, but doesn't match the ServerIdentity.
+var ErrServerIdentityMismatch = errors.New("server certificate doesn't match the expected identity")
+
+// ServerIdentity is the expected identity of the server, verified instead of
+// the host name dialled. This decouples the identity from the DNS names and
+// load balancers the server is reached through. Every non-empty field must be
+// matched by the server certificate, which must still chain to the CA.
+type ServerIdentity struct {
+	// SPIFFEIDs are the accepted SPIFFE IDs, e.g.
+	// "spiffe://example.org/authorize", of which the certificate must have
+	// one as URI SAN.
+	SPIFFEIDs []string
+	// DNSNames are the accepted DNS SANs, of which the certificate must have
+	// one. Wildcards in the certificate are matched as in TLS.
+	DNSNames []string
+	// PublicKeyPins are base64 encoded SHA-256 hashes of the
+	// SubjectPublicKeyInfo of accepted keys, of which the leaf or one of the
+	// certificates it chains through must have one.
+	PublicKeyPins []string
+}
+
+// WithServerIdentity verifies the server certificate against identity rather
+// than the host name dialled.
+func WithServerIdentity(identity ServerIdentity) CredentialsOption {
+	return func(c *autoRefreshingTransportCredentials) {
+		c.identity = &identity
+	}
+}
+
+func (id *ServerIdentity) validate() error {
+	if len(id.SPIFFEIDs) == 0 && len(id.DNSNames) == 0 && len(id.PublicKeyPins) == 0 {
+		return errors.New("server identity has neither SPIFFE IDs, DNS names nor public key pins")
+	}
+
+	for _, spiffeID := range id.SPIFFEIDs {
+		u, err := url.Parse(spiffeID)
+		if err != nil || u.Scheme != "spiffe" || u.Host == "" {
+			return fmt.Errorf("invalid SPIFFE ID '%s'", spiffeID)
+		}
+	}
+
+	for _, pin := range id.PublicKeyPins {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return fmt.Errorf("invalid public key pin '%s', expected a base64 encoded SHA-256 hash", pin)
+		}
+	}
+
+	return nil
+}
+
+// PublicKeyPin returns the pin of the public key of cert, as expected by
+// ServerIdentity.PublicKeyPins.
+func PublicKeyPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// verifyServerIdentity configures config to verify the chain of the server
+// certificate and its identity, without checking the server name.
+func verifyServerIdentity(config *tls.Config, identity *ServerIdentity) {
+	roots := config.RootCAs
+
+	config.InsecureSkipVerify = true
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("failed to parse server certificate: %w", err)
+			}
+
+			certs[i] = cert
+		}
+
+		if len(certs) == 0 {
+			return errors.New("server presented no certificate")
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		chains, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   time.Now(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		})
+		if err != nil {
+			// The same error as returned by the standard verification, which
+			// makes version fallback work alike.
+			return &tls.CertificateVerificationError{UnverifiedCertificates: certs, Err: err}
+		}
+
+		return identity.match(chains)
+	}
+}
+
+func (id *ServerIdentity) match(chains [][]*x509.Certificate) error {
+	leaf := chains[0][0]
+
+	if len(id.SPIFFEIDs) > 0 && !slices.ContainsFunc(leaf.URIs, func(u *url.URL) bool {
+		return slices.Contains(id.SPIFFEIDs, u.String())
+	}) {
+		return fmt.Errorf("%w: no URI SAN is one of the SPIFFE IDs %s", ErrServerIdentityMismatch, strings.Join(id.SPIFFEIDs, ", "))
+	}
+
+	if len(id.DNSNames) > 0 && !slices.ContainsFunc(id.DNSNames, func(name string) bool {
+		return leaf.VerifyHostname(name) == nil
+	}) {
+		return fmt.Errorf("%w: not valid for any of %s", ErrServerIdentityMismatch, strings.Join(id.DNSNames, ", "))
+	}
+
+	if len(id.PublicKeyPins) > 0 && !slices.ContainsFunc(chains, func(chain []*x509.Certificate) bool {
+		return slices.ContainsFunc(chain, func(cert *x509.Certificate) bool {
+			return slices.Contains(id.PublicKeyPins, PublicKeyPin(cert))
+		})
+	}) {
+		return fmt.Errorf("%w: no public key matches a pin", ErrServerIdentityMismatch)
+	}
+
+	return nil
+}
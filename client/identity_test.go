@@ -0,0 +1,82 @@
+package client_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	"github.com/SKF/go-enlight-authorizer/pkitest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ServerIdentity(t *testing.T) {
+	ds := datastoreValidFor(t, DistantFuture)
+	spiffe := issue(t, pkitest.WithURIs("spiffe://example.org/authorize"))
+
+	block, _ := pem.Decode(ds.Crt)
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		identity client.ServerIdentity
+		server   credentialsmanager.DataStore
+		err      error
+	}{
+		"DNS name":                {identity: client.ServerIdentity{DNSNames: []string{"authorize.internal", "localhost"}}, server: ds},
+		"DNS name mismatch":       {identity: client.ServerIdentity{DNSNames: []string{"authorize.internal"}}, server: ds, err: client.ErrServerIdentityMismatch},
+		"SPIFFE ID":               {identity: client.ServerIdentity{SPIFFEIDs: []string{"spiffe://example.org/authorize"}}, server: spiffe},
+		"SPIFFE ID mismatch":      {identity: client.ServerIdentity{SPIFFEIDs: []string{"spiffe://example.org/other"}}, server: spiffe, err: client.ErrServerIdentityMismatch},
+		"public key pin":          {identity: client.ServerIdentity{PublicKeyPins: []string{client.PublicKeyPin(leaf)}}, server: ds},
+		"public key pin mismatch": {identity: client.ServerIdentity{PublicKeyPins: []string{"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}}, server: ds, err: client.ErrServerIdentityMismatch},
+		"SPIFFE ID and DNS name":  {identity: client.ServerIdentity{SPIFFEIDs: []string{"spiffe://example.org/authorize"}, DNSNames: []string{"localhost"}}, server: spiffe, err: client.ErrServerIdentityMismatch},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The host dialled doesn't match the certificate, which is only
+			// verified against the identity.
+			creds, err := client.NewAutoRefreshingTransportCredentials(context.Background(), &rotatingFetcher{ds: ds}, "secret", "lb.example.com",
+				client.WithServerIdentity(tc.identity),
+			)
+			require.NoError(t, err)
+
+			err = handshake(t, creds, tc.server)
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+
+	creds, err := client.NewAutoRefreshingTransportCredentials(context.Background(), &rotatingFetcher{ds: ds}, "secret", "lb.example.com",
+		client.WithServerIdentity(client.ServerIdentity{DNSNames: []string{"localhost"}}),
+	)
+	require.NoError(t, err)
+	assert.Error(t, handshake(t, creds, untrustedDatastore(t)), "The certificate must still chain to the CA")
+}
+
+func Test_ServerIdentity_Invalid(t *testing.T) {
+	ds := datastoreValidFor(t, DistantFuture)
+
+	for name, identity := range map[string]client.ServerIdentity{
+		"empty":          {},
+		"bad SPIFFE ID":  {SPIFFEIDs: []string{"https://example.org/authorize"}},
+		"bad public key": {PublicKeyPins: []string{"not a pin"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.NewAutoRefreshingTransportCredentials(context.Background(), &rotatingFetcher{ds: ds}, "secret", "localhost",
+				client.WithServerIdentity(identity),
+			)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := client.NewServerTransportCredentials(context.Background(), &rotatingFetcher{ds: ds}, "secret",
+		client.WithServerIdentity(client.ServerIdentity{DNSNames: []string{"localhost"}}),
+	)
+	assert.Error(t, err)
+}
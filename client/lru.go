@@ -0,0 +1,89 @@
+package client
+
+import "container/list"
+
+// lru is a size bounded map which evicts the least recently used entry
+// when full. It is not safe for concurrent use.
+type lru[K comparable, V any] struct {
+	capacity int
+	ll       *list.List
+	items    map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRU[K comparable, V any](capacity int) *lru[K, V] {
+	return &lru[K, V]{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[K]*list.Element),
+	}
+}
+
+func (l *lru[K, V]) Get(key K) (value V, ok bool) {
+	element, ok := l.items[key]
+	if !ok {
+		return
+	}
+
+	l.ll.MoveToFront(element)
+
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+// Add inserts or updates the value for key and reports whether another
+// entry had to be evicted to make room for it.
+func (l *lru[K, V]) Add(key K, value V) (evicted bool) {
+	if element, ok := l.items[key]; ok {
+		l.ll.MoveToFront(element)
+		element.Value.(*lruEntry[K, V]).value = value
+
+		return false
+	}
+
+	l.items[key] = l.ll.PushFront(&lruEntry[K, V]{key: key, value: value})
+
+	if l.capacity > 0 && l.ll.Len() > l.capacity {
+		l.removeElement(l.ll.Back())
+		return true
+	}
+
+	return false
+}
+
+func (l *lru[K, V]) Remove(key K) {
+	if element, ok := l.items[key]; ok {
+		l.removeElement(element)
+	}
+}
+
+// RemoveFunc removes every entry for which fn returns true.
+func (l *lru[K, V]) RemoveFunc(fn func(key K, value V) bool) {
+	for element := l.ll.Front(); element != nil; {
+		next := element.Next()
+
+		entry := element.Value.(*lruEntry[K, V])
+		if fn(entry.key, entry.value) {
+			l.removeElement(element)
+		}
+
+		element = next
+	}
+}
+
+func (l *lru[K, V]) Purge() {
+	l.ll.Init()
+	l.items = make(map[K]*list.Element)
+}
+
+func (l *lru[K, V]) Len() int {
+	return l.ll.Len()
+}
+
+func (l *lru[K, V]) removeElement(element *list.Element) {
+	l.ll.Remove(element)
+	delete(l.items, element.Value.(*lruEntry[K, V]).key)
+}
@@ -0,0 +1,243 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	authorizeApi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMetricsNamespace prefixes the names of the metrics.
+const DefaultMetricsNamespace = "authorize_client"
+
+// DefaultBatchSizeBuckets are the buckets of the batch size histogram.
+var DefaultBatchSizeBuckets = prometheus.ExponentialBuckets(1, 4, 8)
+
+// MetricsConfig configures the metrics created by NewMetrics.
+type MetricsConfig struct {
+	// Namespace prefixes the names of the metrics, DefaultMetricsNamespace by
+	// default.
+	Namespace string
+	// LatencyBuckets are the buckets of the latency histogram in seconds,
+	// prometheus.DefBuckets by default.
+	LatencyBuckets []float64
+	// BatchSizeBuckets are the buckets of the batch size histogram,
+	// DefaultBatchSizeBuckets by default.
+	BatchSizeBuckets []float64
+}
+
+// Metrics is a Prometheus collector of the calls made by the clients created
+// with WithMetrics, their decisions, certificates and connections. It is
+// registered by the caller, e.g. with prometheus.MustRegister, and can be
+// shared by several clients.
+type Metrics struct {
+	latency   *prometheus.HistogramVec
+	errors    *prometheus.CounterVec
+	decisions *prometheus.CounterVec
+	batchSize *prometheus.HistogramVec
+
+	certificateExpiry *prometheus.Desc
+	connectionState   *prometheus.Desc
+
+	mu          sync.Mutex
+	credentials map[*autoRefreshingTransportCredentials]string
+	conns       map[*grpc.ClientConn]string
+}
+
+var _ prometheus.Collector = &Metrics{}
+
+// NewMetrics creates the metrics collector.
+func NewMetrics(config MetricsConfig) *Metrics {
+	if config.Namespace == "" {
+		config.Namespace = DefaultMetricsNamespace
+	}
+
+	if config.LatencyBuckets == nil {
+		config.LatencyBuckets = prometheus.DefBuckets
+	}
+
+	if config.BatchSizeBuckets == nil {
+		config.BatchSizeBuckets = DefaultBatchSizeBuckets
+	}
+
+	return &Metrics{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the calls to the authorize service, including retries.",
+			Buckets:   config.LatencyBuckets,
+		}, []string{"method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Name:      "errors_total",
+			Help:      "Failed calls to the authorize service by gRPC status code.",
+		}, []string{"method", "code"}),
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Name:      "decisions_total",
+			Help:      "Authorization decisions by action and resource type.",
+		}, []string{"action", "resource_type", "decision"}),
+		batchSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Name:      "batch_size",
+			Help:      "Number of resources in the bulk calls to the authorize service.",
+			Buckets:   config.BatchSizeBuckets,
+		}, []string{"method"}),
+		certificateExpiry: prometheus.NewDesc(
+			prometheus.BuildFQName(config.Namespace, "", "certificate_expiry_seconds"),
+			"Time until the client certificate in use expires.",
+			[]string{"secret"}, nil,
+		),
+		connectionState: prometheus.NewDesc(
+			prometheus.BuildFQName(config.Namespace, "", "connection_state"),
+			"State of the connection to the authorize service, 1 for the current state.",
+			[]string{"target", "state"}, nil,
+		),
+		credentials: map[*autoRefreshingTransportCredentials]string{},
+		conns:       map[*grpc.ClientConn]string{},
+	}
+}
+
+// WithMetrics records the calls of the client, its certificate and
+// connection state in metrics.
+func WithMetrics(metrics *Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.latency.Describe(ch)
+	m.errors.Describe(ch)
+	m.decisions.Describe(ch)
+	m.batchSize.Describe(ch)
+	ch <- m.certificateExpiry
+	ch <- m.connectionState
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.latency.Collect(ch)
+	m.errors.Collect(ch)
+	m.decisions.Collect(ch)
+	m.batchSize.Collect(ch)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for creds, secret := range m.credentials {
+		_, expiry := creds.current()
+		ch <- prometheus.MustNewConstMetric(m.certificateExpiry, prometheus.GaugeValue, time.Until(expiry).Seconds(), secret)
+	}
+
+	for conn, target := range m.conns {
+		current := conn.GetState()
+		for _, state := range []connectivity.State{
+			connectivity.Idle,
+			connectivity.Connecting,
+			connectivity.Ready,
+			connectivity.TransientFailure,
+			connectivity.Shutdown,
+		} {
+			value := 0.0
+			if state == current {
+				value = 1
+			}
+
+			ch <- prometheus.MustNewConstMetric(m.connectionState, prometheus.GaugeValue, value, target, state.String())
+		}
+	}
+}
+
+// watch reports the certificate, if loaded by the client, and the connection
+// state of a client until the returned closer is closed.
+func (m *Metrics) watch(target string, conn *grpc.ClientConn, creds *autoRefreshingTransportCredentials) closerFunc {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.conns[conn] = target
+	if creds != nil {
+		m.credentials[creds] = creds.secretKeyName
+	}
+
+	return func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		delete(m.conns, conn)
+		delete(m.credentials, creds)
+
+		return nil
+	}
+}
+
+func (m *Metrics) interceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		_, name := splitMethod(method)
+
+		if r, ok := req.(interface{ GetResources() []*common.Origin }); ok {
+			m.batchSize.WithLabelValues(name).Observe(float64(len(r.GetResources())))
+		}
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		m.latency.WithLabelValues(name).Observe(time.Since(start).Seconds())
+
+		if err != nil {
+			m.errors.WithLabelValues(name, status.Code(err).String()).Inc()
+			return err
+		}
+
+		m.observeDecisions(req, reply)
+
+		return nil
+	}
+}
+
+func (m *Metrics) observeDecisions(req, reply interface{}) {
+	var action string
+	if r, ok := req.(interface{ GetAction() string }); ok {
+		action = r.GetAction()
+	}
+
+	var resourceType string
+	if r, ok := req.(interface{ GetResource() *common.Origin }); ok {
+		resourceType = r.GetResource().GetType()
+	}
+
+	switch r := reply.(type) {
+	case *authorizeApi.IsAuthorizedBulkOutput:
+		resources := req.(*authorizeApi.IsAuthorizedBulkInput).GetResources()
+
+		for i, response := range r.GetResponses() {
+			resource := response.GetResource()
+			if resource == nil && i < len(resources) {
+				resource = resources[i]
+			}
+
+			m.decisions.WithLabelValues(action, resource.GetType(), decisionLabel(response.GetOk())).Inc()
+		}
+	case interface{ GetOk() bool }:
+		m.decisions.WithLabelValues(action, resourceType, decisionLabel(r.GetOk())).Inc()
+	}
+}
+
+func decisionLabel(ok bool) string {
+	if ok {
+		return "allow"
+	}
+
+	return "deny"
+}
+
+// closerFunc is an io.Closer calling the function.
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
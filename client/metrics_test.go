@@ -0,0 +1,135 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	"github.com/SKF/go-enlight-authorizer/pkitest"
+	grpcapi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricValue returns the value of the metric with the given name and
+// labels, and whether it was found.
+func metricValue(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) (float64, bool) {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			if !hasLabels(metric, labels) {
+				continue
+			}
+
+			switch {
+			case metric.Counter != nil:
+				return metric.GetCounter().GetValue(), true
+			case metric.Gauge != nil:
+				return metric.GetGauge().GetValue(), true
+			case metric.Histogram != nil:
+				return float64(metric.GetHistogram().GetSampleCount()), true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func hasLabels(metric *dto.Metric, labels map[string]string) bool {
+	matched := 0
+	for _, label := range metric.GetLabel() {
+		if value, ok := labels[label.GetName()]; ok && value == label.GetValue() {
+			matched++
+		}
+	}
+
+	return matched == len(labels)
+}
+
+func Test_Metrics(t *testing.T) {
+	server, err := authMock.NewStatefulServer()
+	require.NoError(t, err)
+	defer server.Stop(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	site := &common.Origin{Id: "site", Type: "site", Provider: "hierarchy"}
+	asset := &common.Origin{Id: "asset", Type: "asset", Provider: "hierarchy"}
+	require.NoError(t, server.Store.AddResources(ctx, []*common.Origin{site, asset}))
+	require.NoError(t, server.Store.AddAction(ctx, &grpcapi.Action{Name: "read"}))
+	require.NoError(t, server.Store.ApplyUserAction(ctx, "user", "read", site))
+
+	metrics := authorize.NewMetrics(authorize.MetricsConfig{})
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(metrics))
+
+	target := net.JoinHostPort(server.HostPort())
+	c, err := authorize.New(ctx, target, authorize.WithInsecure(), authorize.WithMetrics(metrics))
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.IsAuthorized(ctx, "user", "read", asset)
+	require.NoError(t, err)
+
+	_, _, err = c.IsAuthorizedBulk(ctx, "user", "read", []*common.Origin{site, asset, {Id: "other", Type: "asset"}})
+	require.NoError(t, err)
+
+	_, err = c.GetResource(ctx, "missing", "asset")
+	require.Error(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{name: "authorize_client_request_duration_seconds", labels: map[string]string{"method": "IsAuthorized"}, value: 1},
+		{name: "authorize_client_request_duration_seconds", labels: map[string]string{"method": "GetResource"}, value: 1},
+		{name: "authorize_client_errors_total", labels: map[string]string{"method": "GetResource", "code": "NotFound"}, value: 1},
+		{name: "authorize_client_decisions_total", labels: map[string]string{"action": "read", "resource_type": "asset", "decision": "deny"}, value: 3},
+		{name: "authorize_client_decisions_total", labels: map[string]string{"action": "read", "resource_type": "site", "decision": "allow"}, value: 1},
+		{name: "authorize_client_batch_size", labels: map[string]string{"method": "IsAuthorizedBulk"}, value: 1},
+		{name: "authorize_client_connection_state", labels: map[string]string{"target": "dns:///" + target, "state": "READY"}, value: 1},
+		{name: "authorize_client_connection_state", labels: map[string]string{"target": "dns:///" + target, "state": "IDLE"}, value: 0},
+	} {
+		value, ok := metricValue(t, registry, tc.name, tc.labels)
+		if assert.True(t, ok, "%s %v", tc.name, tc.labels) {
+			assert.Equal(t, tc.value, value, "%s %v", tc.name, tc.labels)
+		}
+	}
+
+	require.NoError(t, c.Close())
+
+	_, ok := metricValue(t, registry, "authorize_client_connection_state", nil)
+	assert.False(t, ok, "Closed clients are no longer reported")
+}
+
+func Test_Metrics_CertificateExpiry(t *testing.T) {
+	metrics := authorize.NewMetrics(authorize.MetricsConfig{Namespace: "test"})
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(metrics))
+
+	c, err := authorize.New(context.Background(), "localhost:10000",
+		authorize.WithCredentialsFetcher(pkitest.NewFetcher(issue(t, pkitest.WithValidFor(time.Hour))), "secret"),
+		authorize.WithMetrics(metrics),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	value, ok := metricValue(t, registry, "test_certificate_expiry_seconds", map[string]string{"secret": "secret"})
+	require.True(t, ok)
+	assert.InDelta(t, time.Hour.Seconds(), value, 60)
+}
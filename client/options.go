@@ -0,0 +1,325 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/audit"
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	"github.com/SKF/go-utility/v2/log"
+	authorizeApi "github.com/SKF/proto/v2/authorize"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+// Option configures a client created with New.
+type Option func(*options)
+
+type options struct {
+	requestTimeout       time.Duration
+	credentialsFetcher   credentialsmanager.CredentialsFetcher
+	secretKey            string
+	credentialsOptions   []CredentialsOption
+	transportCredentials credentials.TransportCredentials
+	serviceConfig        string
+	interceptors         []grpc.UnaryClientInterceptor
+	logger               log.Logger
+	keepalive            *keepalive.ClientParameters
+	userAgent            string
+	scheme               string
+	resolvers            []resolver.Builder
+	dialOptions          []grpc.DialOption
+	stateReport          *StateReportConfig
+	tracing              *tracing
+	metrics              *Metrics
+	auditSink            audit.Sink
+	auditCaller          audit.CallerFunc
+}
+
+// WithRequestTimeout sets the deadline given to calls made without one,
+// DefaultRequestTimeout by default.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.requestTimeout = d
+	}
+}
+
+// WithCredentialsFetcher secures the connection with the client certificate
+// stored under secretKey, refreshing it before it expires.
+func WithCredentialsFetcher(cf credentialsmanager.CredentialsFetcher, secretKey string) Option {
+	return func(o *options) {
+		o.credentialsFetcher = cf
+		o.secretKey = secretKey
+	}
+}
+
+// WithCredentialsOptions configures the credentials created for the
+// credentials fetcher, e.g. to refresh them in the background.
+func WithCredentialsOptions(opts ...CredentialsOption) Option {
+	return func(o *options) {
+		o.credentialsOptions = append(o.credentialsOptions, opts...)
+	}
+}
+
+// WithTransportCredentials secures the connection with the given credentials.
+func WithTransportCredentials(creds credentials.TransportCredentials) Option {
+	return func(o *options) {
+		o.transportCredentials = creds
+	}
+}
+
+// WithInsecure disables transport security, e.g. when connecting to a mock
+// server in tests.
+func WithInsecure() Option {
+	return WithTransportCredentials(insecure.NewCredentials())
+}
+
+// WithServiceConfig replaces the default service config, including the retry
+// policies, with the given JSON encoded service config.
+func WithServiceConfig(serviceConfig string) Option {
+	return func(o *options) {
+		o.serviceConfig = serviceConfig
+	}
+}
+
+// WithUnaryInterceptors adds interceptors which are invoked, in order, for
+// every call made by the client.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// WithLogger logs every failed call to the given logger.
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithKeepalive sets the keepalive parameters of the connection.
+func WithKeepalive(params keepalive.ClientParameters) Option {
+	return func(o *options) {
+		o.keepalive = &params
+	}
+}
+
+// WithUserAgent sets the user agent sent to the service.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithScheme sets the scheme used to resolve targets given without one,
+// DefaultScheme by default. It can be any of the schemes known to gRPC, such as
+// dns, passthrough or unix, or the scheme of a resolver passed to
+// WithResolvers.
+func WithScheme(scheme string) Option {
+	return func(o *options) {
+		o.scheme = scheme
+	}
+}
+
+// WithResolvers registers resolvers local to the client, which take
+// precedence over the globally registered ones.
+func WithResolvers(resolvers ...resolver.Builder) Option {
+	return func(o *options) {
+		o.resolvers = append(o.resolvers, resolvers...)
+	}
+}
+
+// WithDialOptions passes additional dial options to the underlying
+// connection. They are applied after the options set by the client.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+func (o *options) validate(target string) error {
+	if target == "" {
+		return errors.New("target is required")
+	}
+
+	if o.requestTimeout <= 0 {
+		return errors.New("request timeout must be positive")
+	}
+
+	if o.credentialsFetcher != nil && o.transportCredentials != nil {
+		return errors.New("credentials fetcher and transport credentials are mutually exclusive")
+	}
+
+	if o.credentialsFetcher == nil && o.transportCredentials == nil {
+		return errors.New("transport credentials are required, use WithCredentialsFetcher, WithTransportCredentials or WithInsecure")
+	}
+
+	if o.credentialsFetcher != nil && o.secretKey == "" {
+		return errors.New("secret key is required together with a credentials fetcher")
+	}
+
+	if o.scheme == "" {
+		return errors.New("scheme must not be empty")
+	}
+
+	if o.serviceConfig == "" {
+		return errors.New("service config must not be empty")
+	}
+
+	return nil
+}
+
+// New creates a client connected to target, given as host:port or as a
+// gRPC target including the scheme, e.g. passthrough:///host:port. The
+// configuration is validated and the credentials are loaded before New
+// returns, and the returned client is ready to be used.
+func New(ctx context.Context, target string, opts ...Option) (AuthorizeClient, error) {
+	o := options{
+		requestTimeout: DefaultRequestTimeout,
+		serviceConfig:  defaultServiceConfig,
+		scheme:         DefaultScheme,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := o.validate(target); err != nil {
+		return nil, err
+	}
+
+	target = withScheme(o.scheme, target)
+
+	c := &client{}
+	c.requestTimeout.Store(int64(o.requestTimeout))
+
+	creds := o.transportCredentials
+	if o.credentialsFetcher != nil {
+		var err error
+		if creds, err = NewAutoRefreshingTransportCredentials(ctx, o.credentialsFetcher, o.secretKey, hostOf(target), o.credentialsOptions...); err != nil {
+			return nil, err
+		}
+
+		c.closers = append(c.closers, creds.(io.Closer))
+	}
+
+	interceptors := append([]grpc.UnaryClientInterceptor{c.requestTimeoutInterceptor}, o.interceptors...)
+	if o.logger != nil {
+		interceptors = append(interceptors, loggingInterceptor(o.logger))
+	}
+
+	if o.auditSink != nil {
+		interceptors = append([]grpc.UnaryClientInterceptor{auditInterceptor(o.auditSink, o.auditCaller, o.logger)}, interceptors...)
+	}
+
+	if o.metrics != nil {
+		interceptors = append([]grpc.UnaryClientInterceptor{o.metrics.interceptor()}, interceptors...)
+	}
+
+	// The span covers the whole call, including the request timeout and the
+	// other interceptors.
+	if o.tracing != nil {
+		interceptors = append([]grpc.UnaryClientInterceptor{o.tracing.interceptor()}, interceptors...)
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(o.serviceConfig),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	}
+
+	if o.tracing != nil {
+		dialOpts = append(dialOpts, grpc.WithStatsHandler(attemptCounter{}))
+	}
+
+	if o.keepalive != nil {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(*o.keepalive))
+	}
+
+	if o.userAgent != "" {
+		dialOpts = append(dialOpts, grpc.WithUserAgent(o.userAgent))
+	}
+
+	if len(o.resolvers) > 0 {
+		dialOpts = append(dialOpts, grpc.WithResolvers(o.resolvers...))
+	}
+
+	conn, err := grpc.NewClient(target, append(dialOpts, o.dialOptions...)...)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	c.conn = conn
+	c.api = authorizeApi.NewAuthorizeClient(conn)
+
+	if o.metrics != nil {
+		refreshing, _ := creds.(*autoRefreshingTransportCredentials)
+		c.closers = append(c.closers, o.metrics.watch(target, conn, refreshing))
+	}
+
+	if o.stateReport != nil && !o.stateReport.Disabled {
+		c.closers = append(c.closers, startStateReporter(conn, c.api, *o.stateReport))
+	}
+
+	return c, nil
+}
+
+func loggingInterceptor(logger log.Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err != nil {
+			logger.WithTracing(ctx).WithError(err).
+				WithField("method", method).
+				WithField("code", status.Code(err).String()).
+				Warn("authorize call failed")
+		}
+
+		return err
+	}
+}
+
+// DefaultScheme is the scheme used to resolve targets given without one.
+const DefaultScheme = "dns"
+
+// withScheme prefixes target with scheme unless it already has one. The
+// scheme is always explicit, as the default scheme of gRPC is process-wide
+// state shared with every other client.
+func withScheme(scheme, target string) string {
+	if strings.Contains(target, "://") || strings.HasPrefix(target, "unix:") {
+		return target
+	}
+
+	if scheme == "unix" {
+		return "unix:" + target
+	}
+
+	return scheme + ":///" + target
+}
+
+func dialTarget(host, port string) string {
+	return withScheme(DefaultScheme, host+":"+port)
+}
+
+// hostOf returns the host of a host:port target, which is the name the
+// server certificate is verified against.
+func hostOf(target string) string {
+	if i := strings.LastIndex(target, "/"); i >= 0 {
+		target = target[i+1:]
+	}
+
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		return target
+	}
+
+	return host
+}
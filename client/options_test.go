@@ -0,0 +1,123 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	"github.com/SKF/go-enlight-authorizer/pkitest"
+	grpcapi "github.com/SKF/proto/v2/authorize"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+func Test_New_Validation(t *testing.T) {
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		target string
+		opts   []authorize.Option
+	}{
+		"missing target":      {opts: []authorize.Option{authorize.WithInsecure()}},
+		"missing credentials": {target: "localhost:10000"},
+		"negative timeout":    {target: "localhost:10000", opts: []authorize.Option{authorize.WithInsecure(), authorize.WithRequestTimeout(-time.Second)}},
+		"missing secret key":  {target: "localhost:10000", opts: []authorize.Option{authorize.WithCredentialsFetcher(&pkitest.Fetcher{}, "")}},
+		"both credentials": {target: "localhost:10000", opts: []authorize.Option{
+			authorize.WithInsecure(),
+			authorize.WithCredentialsFetcher(&pkitest.Fetcher{}, "secret"),
+		}},
+		"invalid service config": {target: "localhost:10000", opts: []authorize.Option{authorize.WithInsecure(), authorize.WithServiceConfig("{")}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := authorize.New(ctx, tc.target, tc.opts...)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_New_Options(t *testing.T) {
+	server, err := authMock.NewServer()
+	require.NoError(t, err)
+
+	host, port := server.HostPort()
+
+	var methods []string
+	interceptor := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok, "The request timeout is applied before the interceptors")
+
+		methods = append(methods, method)
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	client, err := authorize.New(context.Background(), net.JoinHostPort(host, port),
+		authorize.WithInsecure(),
+		authorize.WithRequestTimeout(50*time.Millisecond),
+		authorize.WithUnaryInterceptors(interceptor),
+		authorize.WithUserAgent("options-test"),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	server.On("GetAllActions", mock.Anything, mock.Anything).
+		Return(&grpcapi.GetAllActionsOutput{}, nil).
+		After(time.Second)
+
+	_, err = client.GetAllActions(context.Background())
+	assert.ErrorIs(t, err, authorize.ErrDeadlineExceeded, "The request timeout applies to insecure connections too")
+	assert.Equal(t, []string{"/grpcapi.Authorize/GetAllActions"}, methods)
+}
+
+func Test_New_Schemes(t *testing.T) {
+	server, err := authMock.NewServer()
+	require.NoError(t, err)
+
+	host, port := server.HostPort()
+	addr := net.JoinHostPort(host, port)
+
+	server.On("GetAllActions", mock.Anything, mock.Anything).
+		Return(&grpcapi.GetAllActionsOutput{}, nil)
+
+	custom := manual.NewBuilderWithScheme("authorize-test")
+	custom.InitialState(resolver.State{Addresses: []resolver.Address{{Addr: addr}}})
+
+	defaultScheme := resolver.GetDefaultScheme()
+
+	for name, tc := range map[string]struct {
+		target string
+		opts   []authorize.Option
+	}{
+		"default":     {target: addr},
+		"passthrough": {target: addr, opts: []authorize.Option{authorize.WithScheme("passthrough")}},
+		"explicit":    {target: "passthrough:///" + addr},
+		"custom":      {target: "authorize", opts: []authorize.Option{authorize.WithScheme("authorize-test"), authorize.WithResolvers(custom)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, err := authorize.New(context.Background(), tc.target, append(tc.opts, authorize.WithInsecure())...)
+			require.NoError(t, err)
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			_, err = client.GetAllActions(ctx)
+			require.NoError(t, err)
+		})
+	}
+
+	client := authorize.CreateClient()
+	require.NoError(t, client.Dial(context.Background(), host, port, grpc.WithTransportCredentials(insecure.NewCredentials())))
+	defer client.Close()
+
+	assert.Equal(t, defaultScheme, resolver.GetDefaultScheme(), "The process-wide default scheme is left untouched")
+}
@@ -4,4 +4,10 @@ const (
 	ReasonResourceNotFound = "resource_not_found"
 	ReasonAccessDenied     = "access_denied"
 	ReasonInternalError    = "internal_error"
+
+	// Reasons of the decisions made by FallbackClient when the service
+	// couldn't be reached.
+	ReasonFallbackFailClosed = "fallback_fail_closed"
+	ReasonFallbackFailOpen   = "fallback_fail_open"
+	ReasonFallbackLastKnown  = "fallback_last_known"
 )
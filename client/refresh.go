@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	DefaultRefreshMinBackoff = time.Second
+	DefaultRefreshMaxBackoff = 5 * time.Minute
+)
+
+// RefreshConfig configures the background refresh of the certificates.
+type RefreshConfig struct {
+	// RefreshBefore is how long before expiry new certificates are fetched,
+	// CertificateGracePeriod if zero.
+	RefreshBefore time.Duration
+	// MinBackoff and MaxBackoff bound the jittered, exponentially growing
+	// delay between failed attempts, DefaultRefreshMinBackoff and
+	// DefaultRefreshMaxBackoff if zero.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnRotate is called after every attempt to fetch new certificates which
+	// either rotated them or failed.
+	OnRotate func(RotationEvent)
+}
+
+// RotationEvent describes the outcome of an attempt to fetch new certificates.
+type RotationEvent struct {
+	// ExpiresAt is when the certificates in use expire.
+	ExpiresAt time.Time
+	// Err is set if the attempt failed, in which case the previous
+	// certificates are still in use.
+	Err error
+	// Failures is the number of consecutive failed attempts.
+	Failures int
+	// VersionStage and VersionID identify the secret version in use, if the
+	// credentials fetcher reports it.
+	VersionStage string
+	VersionID    string
+}
+
+// WithBackgroundRefresh fetches new certificates in the background before the
+// current ones expire, so that handshakes don't depend on the credentials
+// fetcher being available. The refresher runs until the credentials are
+// closed.
+func WithBackgroundRefresh(config RefreshConfig) CredentialsOption {
+	if config.RefreshBefore == 0 {
+		config.RefreshBefore = CertificateGracePeriod
+	}
+
+	if config.MinBackoff == 0 {
+		config.MinBackoff = DefaultRefreshMinBackoff
+	}
+
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = DefaultRefreshMaxBackoff
+	}
+
+	return func(c *autoRefreshingTransportCredentials) {
+		c.refresh = &config
+	}
+}
+
+type refresher struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startRefresher(creds *autoRefreshingTransportCredentials, config RefreshConfig) *refresher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &refresher{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go r.run(ctx, creds, config)
+
+	return r
+}
+
+func (r *refresher) stop() {
+	r.cancel()
+	<-r.done
+}
+
+func (r *refresher) run(ctx context.Context, creds *autoRefreshingTransportCredentials, config RefreshConfig) {
+	defer close(r.done)
+
+	failures := 0
+
+	for {
+		_, expiryTime := creds.current()
+
+		wait := time.Until(expiryTime.Add(-config.RefreshBefore))
+		if failures > 0 {
+			wait = backoff(config, failures)
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := creds.reload(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		state := creds.state.Load()
+
+		switch {
+		case err != nil:
+			failures++
+			config.notify(state.event(err, failures))
+		case time.Until(state.certificateExpiryTime) <= config.RefreshBefore:
+			// The fetched certificates have not been rotated yet, try again
+			// later without reporting a failure.
+			failures++
+			if !state.certificateExpiryTime.Equal(expiryTime) {
+				config.notify(state.event(nil, 0))
+			}
+		default:
+			failures = 0
+			config.notify(state.event(nil, 0))
+		}
+	}
+}
+
+func (config RefreshConfig) notify(event RotationEvent) {
+	if config.OnRotate != nil {
+		config.OnRotate(event)
+	}
+}
+
+// backoff returns the delay before the next attempt, growing exponentially
+// from MinBackoff up to MaxBackoff with up to half of it randomized.
+func backoff(config RefreshConfig, failures int) time.Duration {
+	d := config.MinBackoff
+	for i := 1; i < failures && d < config.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	d = min(d, config.MaxBackoff)
+
+	return d/2 + rand.N(d/2+1)
+}
+
+func (state *credentialsState) event(err error, failures int) RotationEvent {
+	return RotationEvent{
+		ExpiresAt:    state.certificateExpiryTime,
+		Err:          err,
+		Failures:     failures,
+		VersionStage: state.versionStage,
+		VersionID:    state.versionID,
+	}
+}
@@ -0,0 +1,204 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	"github.com/SKF/go-enlight-authorizer/pkitest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/credentials"
+)
+
+// rotatingFetcher is a credentials fetcher which is safe to use from the
+// background refresher.
+type rotatingFetcher struct {
+	mu    sync.Mutex
+	ds    credentialsmanager.DataStore
+	err   error
+	calls int
+}
+
+func (f *rotatingFetcher) GetDataStore(ctx context.Context, secretsName string) (*credentialsmanager.DataStore, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	ds := f.ds
+	return &ds, nil
+}
+
+func (f *rotatingFetcher) set(ds credentialsmanager.DataStore, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.ds, f.err = ds, err
+}
+
+func datastoreValidFor(t *testing.T, validTime time.Duration) credentialsmanager.DataStore {
+	return issue(t, pkitest.WithValidFor(validTime), pkitest.WithDNSNames("localhost"))
+}
+
+func Test_BackgroundRefresh_Rotates(t *testing.T) {
+	cf := &rotatingFetcher{ds: datastoreValidFor(t, time.Hour)}
+	events := make(chan client.RotationEvent, 10)
+
+	creds, err := client.NewAutoRefreshingTransportCredentials(context.Background(), cf, "secret", "localhost",
+		client.WithBackgroundRefresh(client.RefreshConfig{
+			RefreshBefore: time.Hour,
+			MinBackoff:    10 * time.Millisecond,
+			MaxBackoff:    20 * time.Millisecond,
+			OnRotate:      func(event client.RotationEvent) { events <- event },
+		}),
+	)
+	require.NoError(t, err)
+	defer creds.(io.Closer).Close()
+
+	cf.set(datastoreValidFor(t, DistantFuture), nil)
+
+	select {
+	case event := <-events:
+		require.NoError(t, event.Err)
+		assert.True(t, event.ExpiresAt.After(time.Now().Add(time.Hour)), "The rotated certificates are in use")
+	case <-time.After(5 * time.Second):
+		t.Fatal("The certificates were never rotated")
+	}
+}
+
+func Test_BackgroundRefresh_KeepsLastGoodCredentials(t *testing.T) {
+	fetchErr := errors.New("secrets manager is down")
+
+	cf := &rotatingFetcher{ds: datastoreValidFor(t, client.CertificateGracePeriod-time.Second)}
+	events := make(chan client.RotationEvent, 100)
+
+	creds, err := client.NewAutoRefreshingTransportCredentials(context.Background(), cf, "secret", "localhost",
+		client.WithBackgroundRefresh(client.RefreshConfig{
+			MinBackoff: 10 * time.Millisecond,
+			MaxBackoff: 20 * time.Millisecond,
+			OnRotate:   func(event client.RotationEvent) { events <- event },
+		}),
+	)
+	require.NoError(t, err)
+
+	cf.set(credentialsmanager.DataStore{}, fetchErr)
+
+	for failures := 1; failures <= 3; failures++ {
+		select {
+		case event := <-events:
+			require.ErrorIs(t, event.Err, fetchErr)
+			assert.GreaterOrEqual(t, event.Failures, 1)
+		case <-time.After(5 * time.Second):
+			t.Fatal("The failed refresh was never reported")
+		}
+	}
+
+	require.NoError(t, creds.(io.Closer).Close())
+
+	server, conn := net.Pipe()
+	require.NoError(t, server.Close())
+
+	_, _, err = creds.ClientHandshake(context.Background(), "", conn)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, fetchErr, "The handshake uses the certificates which are still valid")
+}
+
+func handshakeConcurrently(t *testing.T, creds credentials.TransportCredentials, n int) {
+	var wg sync.WaitGroup
+
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			server, conn := net.Pipe()
+			assert.NoError(t, server.Close())
+
+			_, _, err := creds.ClientHandshake(context.Background(), "", conn)
+			assert.Error(t, err, "io: read/write on closed pipe")
+			assert.NotNil(t, creds.Info())
+		}()
+	}
+
+	wg.Wait()
+}
+
+func Test_ConcurrentHandshakes_ReloadOnce(t *testing.T) {
+	cf := &rotatingFetcher{ds: datastoreValidFor(t, client.CertificateGracePeriod-time.Second)}
+
+	creds, err := client.NewAutoRefreshingTransportCredentials(context.Background(), cf, "secret", "localhost")
+	require.NoError(t, err)
+
+	cf.set(datastoreValidFor(t, DistantFuture), nil)
+
+	handshakeConcurrently(t, creds, 500)
+	assert.Equal(t, 2, cf.calls, "A single reload is shared by all handshakes")
+}
+
+func Test_ConcurrentHandshakes_FailedReloadOnce(t *testing.T) {
+	cf := &rotatingFetcher{ds: datastoreValidFor(t, client.CertificateGracePeriod-time.Second)}
+
+	creds, err := client.NewAutoRefreshingTransportCredentials(context.Background(), cf, "secret", "localhost")
+	require.NoError(t, err)
+
+	cf.set(credentialsmanager.DataStore{}, errors.New("secrets manager is down"))
+
+	handshakeConcurrently(t, creds, 500)
+	assert.Equal(t, 2, cf.calls, "A failed reload is not retried by every handshake")
+}
+
+type countingFileFetcher struct {
+	*credentialsmanager.FileFetcher
+	calls atomic.Int32
+}
+
+func (f *countingFileFetcher) GetDataStore(ctx context.Context, secretsName string) (*credentialsmanager.DataStore, error) {
+	f.calls.Add(1)
+	return f.FileFetcher.GetDataStore(ctx, secretsName)
+}
+
+func Test_WatchedFetcher_ReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	write := func(ds credentialsmanager.DataStore) {
+		b, err := json.Marshal(ds)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, b, 0o600))
+	}
+
+	write(datastoreValidFor(t, DistantFuture))
+
+	fileFetcher, err := credentialsmanager.NewJSONFileFetcher(path, credentialsmanager.WithPollInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer fileFetcher.Close()
+
+	cf := &countingFileFetcher{FileFetcher: fileFetcher}
+
+	creds, err := client.NewAutoRefreshingTransportCredentials(context.Background(), cf, "secret", "localhost")
+	require.NoError(t, err)
+
+	handshakeConcurrently(t, creds, 10)
+	require.Equal(t, int32(1), cf.calls.Load(), "Unchanged certificates are not reloaded")
+
+	write(datastoreValidFor(t, DistantFuture+time.Hour))
+
+	require.Eventually(t, func() bool { return fileFetcher.Generation() > 0 }, 5*time.Second, 5*time.Millisecond)
+
+	handshakeConcurrently(t, creds, 10)
+	require.Equal(t, int32(2), cf.calls.Load(), "Changed certificates are reloaded once")
+}
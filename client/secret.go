@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
@@ -16,14 +17,52 @@ import (
 
 const CertificateGracePeriod = 24 * time.Hour
 
+// reloadRetryInterval is how long handshakes keep using certificates which
+// are still valid after a reload failed, or didn't return newer certificates,
+// before trying again.
+const reloadRetryInterval = 10 * time.Second
+
 type autoRefreshingTransportCredentials struct {
+	cf            credentialsmanager.CredentialsFetcher
+	secretKeyName string
+	serverName    string
+	refresh       *RefreshConfig
+	refresher     *refresher
+	// server is set for credentials created by NewServerTransportCredentials.
+	server bool
+	// identity replaces the verification of the server name, if set.
+	identity *ServerIdentity
+
+	state atomic.Pointer[credentialsState]
+
+	// stages are the version stages fetched, in order of preference, when
+	// falling back on rejected certificates.
+	stages []string
+
+	// reloading is a semaphore ensuring a single reload at a time. It also
+	// guards retryAfter.
+	reloading  chan struct{}
+	retryAfter time.Time
+}
+
+// credentialsState is swapped atomically on every reload.
+type credentialsState struct {
 	credentials           credentials.TransportCredentials
-	cf                    credentialsmanager.CredentialsFetcher
-	secretKeyName         string
-	serverName            string
 	certificateExpiryTime time.Time
+	// generation of the fetcher the certificates were loaded from, if it
+	// implements credentialsmanager.Watcher.
+	generation   uint64
+	versionStage string
+	versionID    string
+	// stageIndex is the index of versionStage in the stages of the
+	// credentials.
+	stageIndex int
 }
 
+// CredentialsOption configures the transport credentials created by
+// NewAutoRefreshingTransportCredentials.
+type CredentialsOption func(*autoRefreshingTransportCredentials)
+
 func getCredentialOption(ctx context.Context, cf credentialsmanager.CredentialsFetcher, host, secretKeyName string) (grpc.DialOption, error) {
 	c, err := NewAutoRefreshingTransportCredentials(ctx, cf, secretKeyName, host)
 	if err != nil {
@@ -33,36 +72,170 @@ func getCredentialOption(ctx context.Context, cf credentialsmanager.CredentialsF
 	return grpc.WithTransportCredentials(c), nil
 }
 
-func NewAutoRefreshingTransportCredentials(ctx context.Context, cf credentialsmanager.CredentialsFetcher, secretKeyName, host string) (credentials.TransportCredentials, error) {
+// NewAutoRefreshingTransportCredentials creates transport credentials from the
+// certificates stored under secretKeyName, which are reloaded when they are
+// about to expire. The returned credentials implement io.Closer, which stops
+// the background refresher if one was enabled.
+func NewAutoRefreshingTransportCredentials(ctx context.Context, cf credentialsmanager.CredentialsFetcher, secretKeyName, host string, opts ...CredentialsOption) (credentials.TransportCredentials, error) {
 	creds := &autoRefreshingTransportCredentials{
 		secretKeyName: secretKeyName,
 		cf:            cf,
 		serverName:    host,
+		reloading:     make(chan struct{}, 1),
+	}
+
+	return newAutoRefreshingTransportCredentials(ctx, creds, opts)
+}
+
+func newAutoRefreshingTransportCredentials(ctx context.Context, creds *autoRefreshingTransportCredentials, opts []CredentialsOption) (*autoRefreshingTransportCredentials, error) {
+	for _, opt := range opts {
+		opt(creds)
+	}
+
+	if _, ok := creds.cf.(credentialsmanager.StagedFetcher); len(creds.stages) > 0 && !ok {
+		return nil, errors.New("version fallback requires a credentials fetcher supporting version stages")
+	}
+
+	if creds.identity != nil {
+		if creds.server {
+			return nil, errors.New("server identity verification requires client credentials")
+		}
+
+		if err := creds.identity.validate(); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := creds.loadCertificates(ctx); err != nil {
 		return nil, err
 	}
 
+	if creds.refresh != nil {
+		creds.refresher = startRefresher(creds, *creds.refresh)
+	}
+
 	return creds, nil
 }
 
-func (c *autoRefreshingTransportCredentials) ensureValidCredentials(ctx context.Context) error {
-	if !c.shouldLoadNewCertificates() {
-		return nil
+// Close stops the background refresher, if any.
+func (c *autoRefreshingTransportCredentials) Close() error {
+	if c.refresher != nil {
+		c.refresher.stop()
+	}
+
+	return nil
+}
+
+// ensureValidCredentials reloads the certificates when they are about to
+// expire. Concurrent handshakes share a single reload, and a failed reload is
+// tolerated as long as the current certificates are still valid.
+func (c *autoRefreshingTransportCredentials) ensureValidCredentials(ctx context.Context) (*credentialsState, error) {
+	if state := c.state.Load(); !c.shouldReload(state) {
+		return state, nil
+	}
+
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	// Another handshake may have reloaded the certificates while waiting.
+	state := c.state.Load()
+	if !c.shouldReload(state) {
+		return state, nil
+	}
+
+	valid := time.Now().Before(state.certificateExpiryTime)
+	if valid && time.Now().Before(c.retryAfter) {
+		return state, nil
+	}
+
+	if err := c.loadCertificates(ctx); err != nil {
+		if !valid {
+			return nil, err
+		}
+
+		c.retryAfter = time.Now().Add(reloadRetryInterval)
+		c.notify(state.event(err, 0))
+
+		return state, nil
+	}
+
+	state = c.state.Load()
+	if c.shouldReload(state) {
+		c.retryAfter = time.Now().Add(reloadRetryInterval)
+	}
+
+	return state, nil
+}
+
+// reload fetches new certificates, waiting for any reload in progress.
+func (c *autoRefreshingTransportCredentials) reload(ctx context.Context) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
 	}
+	defer c.release()
 
 	return c.loadCertificates(ctx)
 }
 
-func (c *autoRefreshingTransportCredentials) shouldLoadNewCertificates() bool {
-	earliestReload := c.certificateExpiryTime.Add(-CertificateGracePeriod)
+func (c *autoRefreshingTransportCredentials) acquire(ctx context.Context) error {
+	select {
+	case c.reloading <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *autoRefreshingTransportCredentials) release() {
+	<-c.reloading
+}
+
+func (c *autoRefreshingTransportCredentials) current() (credentials.TransportCredentials, time.Time) {
+	state := c.state.Load()
+
+	return state.credentials, state.certificateExpiryTime
+}
+
+func (c *autoRefreshingTransportCredentials) notify(event RotationEvent) {
+	if c.refresh != nil {
+		c.refresh.notify(event)
+	}
+}
+
+// shouldReload reports whether the certificates are about to expire or the
+// fetcher has new ones.
+func (c *autoRefreshingTransportCredentials) shouldReload(state *credentialsState) bool {
+	return shouldLoadNewCertificates(state.certificateExpiryTime) || c.generation() != state.generation
+}
+
+func (c *autoRefreshingTransportCredentials) generation() uint64 {
+	if w, ok := c.cf.(credentialsmanager.Watcher); ok {
+		return w.Generation()
+	}
+
+	return 0
+}
+
+func shouldLoadNewCertificates(expiryTime time.Time) bool {
+	earliestReload := expiryTime.Add(-CertificateGracePeriod)
 
 	return time.Now().After(earliestReload)
 }
 
+// loadCertificates loads the preferred version stage, the stages fallen back
+// on are only used until the next reload.
 func (c *autoRefreshingTransportCredentials) loadCertificates(ctx context.Context) error {
-	config, err := c.loadCertificateIntoConfig(ctx)
+	return c.loadStage(ctx, 0)
+}
+
+func (c *autoRefreshingTransportCredentials) loadStage(ctx context.Context, stageIndex int) error {
+	// The generation is read first, so that changes made while fetching
+	// trigger another reload.
+	generation := c.generation()
+
+	config, secrets, err := c.loadCertificateIntoConfig(ctx, stageIndex)
 	if err != nil {
 		return err
 	}
@@ -82,74 +255,137 @@ func (c *autoRefreshingTransportCredentials) loadCertificates(ctx context.Contex
 		}
 	}
 
-	c.credentials = credentials.NewTLS(config)
-	c.certificateExpiryTime = expiryTime
+	c.state.Store(&credentialsState{
+		credentials:           credentials.NewTLS(config),
+		certificateExpiryTime: expiryTime,
+		generation:            generation,
+		versionStage:          secrets.VersionStage,
+		versionID:             secrets.VersionID,
+		stageIndex:            stageIndex,
+	})
 
 	return nil
 }
 
-func (c *autoRefreshingTransportCredentials) loadCertificateIntoConfig(ctx context.Context) (*tls.Config, error) {
-	secrets, err := c.cf.GetDataStore(ctx, c.secretKeyName)
+func (c *autoRefreshingTransportCredentials) fetchDataStore(ctx context.Context, stageIndex int) (*credentialsmanager.DataStore, error) {
+	if len(c.stages) == 0 {
+		return c.cf.GetDataStore(ctx, c.secretKeyName)
+	}
+
+	return c.cf.(credentialsmanager.StagedFetcher).GetDataStoreVersion(ctx, c.secretKeyName, c.stages[stageIndex])
+}
+
+func (c *autoRefreshingTransportCredentials) loadCertificateIntoConfig(ctx context.Context, stageIndex int) (*tls.Config, *credentialsmanager.DataStore, error) {
+	secrets, err := c.fetchDataStore(ctx, stageIndex)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if _, err = secrets.ValidateUsage(c.usage()); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", c.secretKeyName, err)
 	}
 
 	certificate, err := tls.X509KeyPair(secrets.Crt, secrets.Key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load client certs: %w", err)
+		return nil, nil, fmt.Errorf("failed to load client certs: %w", err)
 	}
 
 	certPool := x509.NewCertPool()
 	ok := certPool.AppendCertsFromPEM(secrets.CA)
 	if !ok {
-		return nil, errors.New("failed to append certs")
+		return nil, nil, errors.New("failed to append certs")
+	}
+
+	if c.server {
+		return serverConfig(certificate, certPool), secrets, nil
 	}
 
-	return &tls.Config{
+	config := &tls.Config{
 		ServerName:   c.serverName,
 		Certificates: []tls.Certificate{certificate},
 		RootCAs:      certPool,
-	}, nil
+	}
+
+	if c.identity != nil {
+		verifyServerIdentity(config, c.identity)
+	}
+
+	return config, secrets, nil
 }
 
 func (c *autoRefreshingTransportCredentials) ClientHandshake(ctx context.Context, s string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
-	if err := c.ensureValidCredentials(ctx); err != nil {
+	state, err := c.ensureValidCredentials(ctx)
+	if err != nil {
 		return nil, nil, err
 	}
 
-	return c.credentials.ClientHandshake(ctx, s, conn)
+	secureConn, authInfo, err := state.credentials.ClientHandshake(ctx, s, conn)
+	if err != nil && len(c.stages) > 1 && isCertificateRejection(err) {
+		c.fallBack(ctx, state)
+	}
+
+	return secureConn, authInfo, err
 }
 
 func (c *autoRefreshingTransportCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
-	if err := c.ensureValidCredentials(context.Background()); err != nil {
+	state, err := c.ensureValidCredentials(context.Background())
+	if err != nil {
 		return nil, nil, err
 	}
 
-	return c.credentials.ServerHandshake(conn)
+	return state.credentials.ServerHandshake(conn)
 }
 
 func (c *autoRefreshingTransportCredentials) Info() credentials.ProtocolInfo {
-	if c.credentials == nil {
-		return credentials.ProtocolInfo{}
-	}
+	creds, _ := c.current()
 
-	return c.credentials.Info()
+	return creds.Info()
 }
 
+// Clone returns a copy of the credentials. The copy is not refreshed in the
+// background, and reloads its certificates during handshakes only.
 func (c *autoRefreshingTransportCredentials) Clone() credentials.TransportCredentials {
-	return &autoRefreshingTransportCredentials{
-		credentials:           c.credentials.Clone(),
-		cf:                    c.cf,
-		secretKeyName:         c.secretKeyName,
-		serverName:            c.serverName,
-		certificateExpiryTime: c.certificateExpiryTime,
+	state := c.state.Load()
+
+	clone := &autoRefreshingTransportCredentials{
+		cf:            c.cf,
+		secretKeyName: c.secretKeyName,
+		serverName:    c.serverName,
+		refresh:       c.refresh,
+		stages:        c.stages,
+		server:        c.server,
+		identity:      c.identity,
+		reloading:     make(chan struct{}, 1),
 	}
+
+	clone.state.Store(&credentialsState{
+		credentials:           state.credentials.Clone(),
+		certificateExpiryTime: state.certificateExpiryTime,
+		generation:            state.generation,
+		versionStage:          state.versionStage,
+		versionID:             state.versionID,
+		stageIndex:            state.stageIndex,
+	})
+
+	return clone
 }
 
+// OverrideServerName overrides the server name of the current certificates,
+// which is lost when the certificates are reloaded.
 func (c *autoRefreshingTransportCredentials) OverrideServerName(s string) error {
-	if c.credentials == nil {
-		return nil
-	}
+	for {
+		state := c.state.Load()
 
-	return c.credentials.OverrideServerName(s) //nolint:staticcheck
+		creds := state.credentials.Clone()
+		if err := creds.OverrideServerName(s); err != nil { //nolint:staticcheck
+			return err
+		}
+
+		overridden := *state
+		overridden.credentials = creds
+
+		if c.state.CompareAndSwap(state, &overridden) {
+			return nil
+		}
+	}
 }
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/url"
+
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// ErrNoPeerCertificate is returned by PeerIdentity when the peer of a request
+// didn't authenticate with a verified client certificate.
+var ErrNoPeerCertificate = errors.New("no verified peer certificate")
+
+// NewServerTransportCredentials creates transport credentials for a gRPC
+// server from the certificates stored under secretKeyName, in the same layout
+// as for clients. Clients are required to present a certificate issued by the
+// CA of the secret, and the server certificate is reloaded when it is about to
+// expire. The certificate must allow server authentication. The options are
+// the same as for NewAutoRefreshingTransportCredentials, and the returned
+// credentials implement io.Closer as well.
+func NewServerTransportCredentials(ctx context.Context, cf credentialsmanager.CredentialsFetcher, secretKeyName string, opts ...CredentialsOption) (credentials.TransportCredentials, error) {
+	creds := &autoRefreshingTransportCredentials{
+		secretKeyName: secretKeyName,
+		cf:            cf,
+		server:        true,
+		reloading:     make(chan struct{}, 1),
+	}
+
+	return newAutoRefreshingTransportCredentials(ctx, creds, opts)
+}
+
+func serverConfig(certificate tls.Certificate, clientCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// usage is the extended key usage required of the certificates.
+func (c *autoRefreshingTransportCredentials) usage() x509.ExtKeyUsage {
+	if c.server {
+		return x509.ExtKeyUsageServerAuth
+	}
+
+	return x509.ExtKeyUsageClientAuth
+}
+
+// Identity describes the verified certificate of the peer of a request.
+type Identity struct {
+	Certificate  *x509.Certificate
+	Subject      string
+	CommonName   string
+	DNSNames     []string
+	URIs         []*url.URL
+	SerialNumber string
+}
+
+// PeerIdentity returns the identity of the client of a request handled by a
+// server using NewServerTransportCredentials, or any other credentials
+// verifying client certificates.
+func PeerIdentity(ctx context.Context) (Identity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return Identity{}, ErrNoPeerCertificate
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return Identity{}, ErrNoPeerCertificate
+	}
+
+	cert := info.State.VerifiedChains[0][0]
+
+	return Identity{
+		Certificate:  cert,
+		Subject:      cert.Subject.String(),
+		CommonName:   cert.Subject.CommonName,
+		DNSNames:     cert.DNSNames,
+		URIs:         cert.URIs,
+		SerialNumber: cert.SerialNumber.String(),
+	}, nil
+}
@@ -0,0 +1,111 @@
+package client_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	"github.com/SKF/go-enlight-authorizer/pkitest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// serverHandshake performs a handshake between the server credentials and a
+// TLS client using clientConfig, returning the auth info of both sides.
+func serverHandshake(t *testing.T, creds credentials.TransportCredentials, clientConfig *tls.Config) (credentials.AuthInfo, *tls.ConnectionState, error) {
+	serverConn, clientConn := connPair(t)
+	defer serverConn.Close()
+
+	clientState := make(chan *tls.ConnectionState, 1)
+
+	go func() {
+		defer clientConn.Close()
+
+		conn := tls.Client(clientConn, clientConfig)
+		if err := conn.Handshake(); err != nil {
+			clientState <- nil
+			return
+		}
+
+		state := conn.ConnectionState()
+		clientState <- &state
+	}()
+
+	_, authInfo, err := creds.ServerHandshake(serverConn)
+
+	return authInfo, <-clientState, err
+}
+
+func clientConfig(t *testing.T, ds credentialsmanager.DataStore, withCertificate bool) *tls.Config {
+	config, err := pkitest.ClientTLSConfig(ds, "localhost")
+	require.NoError(t, err)
+
+	if !withCertificate {
+		config.Certificates = nil
+	}
+
+	return config
+}
+
+func Test_ServerTransportCredentials(t *testing.T) {
+	ca, err := testCA()
+	require.NoError(t, err)
+
+	ds, err := ca.Server()
+	require.NoError(t, err)
+
+	sidecar, err := ca.Client(pkitest.WithCommonName("sidecar"), pkitest.WithURIs("spiffe://example.org/sidecar"))
+	require.NoError(t, err)
+
+	creds, err := client.NewServerTransportCredentials(context.Background(), &rotatingFetcher{ds: ds}, "secret")
+	require.NoError(t, err)
+	defer creds.(io.Closer).Close()
+
+	authInfo, _, err := serverHandshake(t, creds, clientConfig(t, sidecar, true))
+	require.NoError(t, err)
+
+	identity, err := client.PeerIdentity(peer.NewContext(context.Background(), &peer.Peer{AuthInfo: authInfo}))
+	require.NoError(t, err)
+	assert.Equal(t, "sidecar", identity.CommonName)
+	require.Len(t, identity.URIs, 1)
+	assert.Equal(t, "spiffe://example.org/sidecar", identity.URIs[0].String())
+
+	_, err = client.NewServerTransportCredentials(context.Background(), &rotatingFetcher{ds: sidecar}, "secret")
+	assert.ErrorIs(t, err, credentialsmanager.ErrKeyUsage, "Client certificates can't be used by servers")
+
+	_, _, err = serverHandshake(t, creds, clientConfig(t, sidecar, false))
+	assert.Error(t, err, "Clients without a certificate are rejected")
+
+	_, _, err = serverHandshake(t, creds, clientConfig(t, untrustedDatastore(t), true))
+	assert.Error(t, err, "Clients with a certificate from another CA are rejected")
+}
+
+func Test_ServerTransportCredentials_Rotates(t *testing.T) {
+	cf := &rotatingFetcher{ds: datastoreValidFor(t, time.Hour)}
+
+	creds, err := client.NewServerTransportCredentials(context.Background(), cf, "secret")
+	require.NoError(t, err)
+
+	rotated := datastoreValidFor(t, DistantFuture)
+	cf.set(rotated, nil)
+
+	_, state, err := serverHandshake(t, creds, clientConfig(t, rotated, true))
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.WithinDuration(t, time.Now().Add(DistantFuture), state.PeerCertificates[0].NotAfter, time.Minute)
+}
+
+func Test_PeerIdentity_NoCertificate(t *testing.T) {
+	_, err := client.PeerIdentity(context.Background())
+	assert.ErrorIs(t, err, client.ErrNoPeerCertificate)
+
+	_, err = client.PeerIdentity(peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{}}))
+	assert.ErrorIs(t, err, client.ErrNoPeerCertificate)
+}
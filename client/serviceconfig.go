@@ -0,0 +1,173 @@
+package client
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+const serviceName = "grpcapi.Authorize"
+
+// RetryPolicy describes how failed calls are retried by the gRPC channel.
+// A policy with MaxAttempts of one or less, or without any retryable status
+// codes, disables retries.
+type RetryPolicy struct {
+	MaxAttempts          int
+	InitialBackoff       time.Duration
+	MaxBackoff           time.Duration
+	BackoffMultiplier    float64
+	RetryableStatusCodes []codes.Code
+}
+
+// DefaultReadRetryPolicy is used for checks and lookups, which are safe to
+// replay and are retried on transient failures only.
+var DefaultReadRetryPolicy = RetryPolicy{
+	MaxAttempts:       5,
+	InitialBackoff:    100 * time.Millisecond,
+	MaxBackoff:        time.Second,
+	BackoffMultiplier: 1.6,
+	RetryableStatusCodes: []codes.Code{
+		codes.Canceled,
+		codes.DeadlineExceeded,
+		codes.ResourceExhausted,
+		codes.Aborted,
+		codes.Unavailable,
+	},
+}
+
+// DefaultWriteRetryPolicy is used for the idempotent writes, see
+// idempotentWriteMethods. They are only retried when the service was
+// unavailable. The request may still have been applied, e.g. when the
+// connection was reset after it was received, which is harmless as applying
+// it twice has the same effect as applying it once. Other writes are never
+// retried.
+var DefaultWriteRetryPolicy = RetryPolicy{
+	MaxAttempts:          3,
+	InitialBackoff:       100 * time.Millisecond,
+	MaxBackoff:           time.Second,
+	BackoffMultiplier:    1.6,
+	RetryableStatusCodes: []codes.Code{codes.Unavailable},
+}
+
+// readMethods are the RPCs which don't modify the state of the service. All
+// other RPCs, including ones added to the service later, are treated as writes.
+var readMethods = []string{
+	"DeepPing",
+	"IsAuthorized",
+	"IsAuthorizedBulk",
+	"IsAuthorizedByEndpoint",
+	"IsAuthorizedWithReason",
+	"GetResource",
+	"GetResourcesByUserAction",
+	"GetResourcesByType",
+	"GetResourceParents",
+	"GetResourceChildren",
+	"GetActionsByUserRole",
+	"GetResourcesAndActionsByUser",
+	"GetResourcesAndActionsByUserAndResource",
+	"GetResourcesByOriginAndType",
+	"GetResourcesWithActionsAccess",
+	"GetUserIDsWithAccessToResource",
+	"GetUserActions",
+	"GetUserRole",
+	"GetAction",
+	"GetAllActions",
+}
+
+// idempotentWriteMethods are the RPCs which modify the state of the service,
+// but can safely be applied more than once as they remove something or set it
+// to a given state. All other writes, such as the Add* RPCs, and RPCs added to
+// the service later aren't retried.
+var idempotentWriteMethods = []string{
+	"RemoveResource",
+	"RemoveResources",
+	"RemoveResourceRelation",
+	"RemoveResourceRelations",
+	"ApplyUserAction",
+	"ApplyRolesForUserOnResources",
+	"RemoveUserAction",
+	"RemoveAction",
+	"RemoveUserRole",
+}
+
+// WithRetryPolicies replaces the default retry policies of the client, write
+// being used for the idempotent writes only. It is
+// passed to Dial or DialUsingCredentialsManager together with the other dial
+// options.
+func WithRetryPolicies(read, write RetryPolicy) grpc.DialOption {
+	return grpc.WithDefaultServiceConfig(serviceConfig(read, write))
+}
+
+var defaultServiceConfig = serviceConfig(DefaultReadRetryPolicy, DefaultWriteRetryPolicy)
+
+type jsonServiceConfig struct {
+	LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig"`
+	MethodConfig        []jsonMethodConfig    `json:"methodConfig"`
+}
+
+type jsonName struct {
+	Service string `json:"service"`
+	Method  string `json:"method,omitempty"`
+}
+
+type jsonMethodConfig struct {
+	Name         []jsonName       `json:"name"`
+	WaitForReady bool             `json:"waitForReady"`
+	RetryPolicy  *jsonRetryPolicy `json:"retryPolicy,omitempty"`
+}
+
+type jsonRetryPolicy struct {
+	MaxAttempts          int          `json:"maxAttempts"`
+	InitialBackoff       string       `json:"initialBackoff"`
+	MaxBackoff           string       `json:"maxBackoff"`
+	BackoffMultiplier    float64      `json:"backoffMultiplier"`
+	RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+}
+
+func serviceConfig(read, write RetryPolicy) string {
+	config := jsonServiceConfig{
+		LoadBalancingConfig: []map[string]struct{}{{"round_robin": {}}},
+		MethodConfig: []jsonMethodConfig{
+			{Name: methodNames(readMethods), WaitForReady: true, RetryPolicy: read.toJSON()},
+			{Name: methodNames(idempotentWriteMethods), WaitForReady: true, RetryPolicy: write.toJSON()},
+			{Name: []jsonName{{Service: serviceName}}, WaitForReady: true},
+		},
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(b)
+}
+
+func methodNames(methods []string) []jsonName {
+	names := make([]jsonName, len(methods))
+	for i, method := range methods {
+		names[i] = jsonName{Service: serviceName, Method: method}
+	}
+
+	return names
+}
+
+func (p RetryPolicy) toJSON() *jsonRetryPolicy {
+	if p.MaxAttempts <= 1 || len(p.RetryableStatusCodes) == 0 {
+		return nil
+	}
+
+	return &jsonRetryPolicy{
+		MaxAttempts:          p.MaxAttempts,
+		InitialBackoff:       seconds(p.InitialBackoff),
+		MaxBackoff:           seconds(p.MaxBackoff),
+		BackoffMultiplier:    p.BackoffMultiplier,
+		RetryableStatusCodes: p.RetryableStatusCodes,
+	}
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
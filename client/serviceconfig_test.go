@@ -0,0 +1,161 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	grpcapi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func retryClientFor(t *testing.T, server *authMock.AuthorizeServer, opts ...grpc.DialOption) authorize.AuthorizeClient {
+	host, port := server.HostPort()
+
+	client := authorize.CreateClient()
+
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	require.NoError(t, client.Dial(context.Background(), host, port, opts...))
+
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func Test_RetryPolicy_Reads(t *testing.T) {
+	resource := &common.Origin{Id: "0", Type: "node", Provider: "1"}
+
+	for _, tc := range []struct {
+		code  codes.Code
+		calls int
+	}{
+		{code: codes.Unavailable, calls: 2},
+		{code: codes.ResourceExhausted, calls: 2},
+		{code: codes.Aborted, calls: 2},
+		{code: codes.NotFound, calls: 1},
+		{code: codes.PermissionDenied, calls: 1},
+		{code: codes.InvalidArgument, calls: 1},
+		{code: codes.Unauthenticated, calls: 1},
+	} {
+		t.Run(tc.code.String(), func(t *testing.T) {
+			server, err := authMock.NewServer()
+			require.NoError(t, err)
+
+			client := retryClientFor(t, server)
+
+			server.On("IsAuthorized", mock.Anything, mock.Anything).
+				Return((*grpcapi.IsAuthorizedOutput)(nil), status.Error(tc.code, "failure")).Once()
+			server.On("IsAuthorized", mock.Anything, mock.Anything).
+				Return(&grpcapi.IsAuthorizedOutput{Ok: true}, nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_, err = client.IsAuthorized(ctx, "testUser", "testAction", resource)
+			if tc.calls == 1 {
+				assert.Equal(t, tc.code, status.Code(err))
+			} else {
+				assert.NoError(t, err)
+			}
+
+			server.AssertNumberOfCalls(t, "IsAuthorized", tc.calls)
+		})
+	}
+}
+
+func Test_RetryPolicy_Writes(t *testing.T) {
+	resource := &common.Origin{Id: "0", Type: "node", Provider: "1"}
+
+	for _, tc := range []struct {
+		code  codes.Code
+		calls int
+	}{
+		{code: codes.Unavailable, calls: 2},
+		{code: codes.Aborted, calls: 1},
+		{code: codes.DeadlineExceeded, calls: 1},
+		{code: codes.AlreadyExists, calls: 1},
+	} {
+		t.Run(tc.code.String(), func(t *testing.T) {
+			server, err := authMock.NewServer()
+			require.NoError(t, err)
+
+			client := retryClientFor(t, server)
+
+			server.On("ApplyUserAction", mock.Anything, mock.Anything).
+				Return((*common.Void)(nil), status.Error(tc.code, "failure")).Once()
+			server.On("ApplyUserAction", mock.Anything, mock.Anything).
+				Return(&common.Void{}, nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err = client.ApplyUserAction(ctx, "testUser", "testAction", resource)
+			if tc.calls == 1 {
+				assert.Equal(t, tc.code, status.Code(err))
+			} else {
+				assert.NoError(t, err)
+			}
+
+			server.AssertNumberOfCalls(t, "ApplyUserAction", tc.calls)
+		})
+	}
+}
+
+func Test_RetryPolicy_NonIdempotentWrites(t *testing.T) {
+	server, err := authMock.NewServer()
+	require.NoError(t, err)
+
+	client := retryClientFor(t, server)
+
+	server.On("AddResource", mock.Anything, mock.Anything).
+		Return((*common.Void)(nil), status.Error(codes.Unavailable, "failure")).Once()
+	server.On("AddResource", mock.Anything, mock.Anything).
+		Return(&common.Void{}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = client.AddResource(ctx, &common.Origin{Id: "0", Type: "node"})
+	assert.ErrorIs(t, err, authorize.ErrUnavailable, "Writes which may not be applied twice aren't retried")
+
+	server.AssertNumberOfCalls(t, "AddResource", 1)
+}
+
+func Test_RetryPolicy_Override(t *testing.T) {
+	server, err := authMock.NewServer()
+	require.NoError(t, err)
+
+	write := authorize.DefaultWriteRetryPolicy
+	write.RetryableStatusCodes = []codes.Code{codes.Unavailable, codes.Aborted}
+
+	client := retryClientFor(t, server, authorize.WithRetryPolicies(authorize.RetryPolicy{}, write))
+
+	server.On("GetResource", mock.Anything, mock.Anything).
+		Return((*grpcapi.GetResourceOutput)(nil), status.Error(codes.Unavailable, "failure")).Once()
+	server.On("RemoveResource", mock.Anything, mock.Anything).
+		Return((*common.Void)(nil), status.Error(codes.Aborted, "failure")).Once()
+	server.On("RemoveResource", mock.Anything, mock.Anything).
+		Return(&common.Void{}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = client.GetResource(ctx, "0", "node")
+	assert.ErrorIs(t, err, authorize.ErrUnavailable, "Retries of reads are disabled")
+
+	err = client.RemoveResource(ctx, &common.Origin{Id: "0", Type: "node"})
+	assert.NoError(t, err)
+
+	server.AssertNumberOfCalls(t, "GetResource", 1)
+	server.AssertNumberOfCalls(t, "RemoveResource", 2)
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	authorizeApi "github.com/SKF/proto/v2/authorize"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+const (
+	// DefaultStateReportInterval is the least time between two reports of the
+	// connection state.
+	DefaultStateReportInterval = 10 * time.Second
+	// DefaultStateReportBacklog is the number of state transitions kept while
+	// they can't be reported.
+	DefaultStateReportBacklog = 32
+	// stateReportTimeout is the deadline of a single report.
+	stateReportTimeout = 5 * time.Second
+)
+
+// StateReportConfig configures the reporting of the connection state of the
+// client to the service through LogClientState.
+type StateReportConfig struct {
+	// Hostname identifies the client, the host name reported by the kernel
+	// by default.
+	Hostname string
+	// MinInterval is the least time between two reports,
+	// DefaultStateReportInterval by default.
+	MinInterval time.Duration
+	// Backlog is the number of transitions kept while they can't be
+	// reported, as the connection isn't ready, DefaultStateReportBacklog by
+	// default. The oldest transitions are dropped first.
+	Backlog int
+	// Disabled turns reporting off, e.g. from configuration.
+	Disabled bool
+}
+
+// WithStateReporting reports every transition of the connection state, e.g.
+// to TRANSIENT_FAILURE, to the service. Transitions happening while the
+// connection is down are reported once it is ready again. Reporting makes the
+// client connect right away rather than on the first call.
+func WithStateReporting(config StateReportConfig) Option {
+	return func(o *options) {
+		o.stateReport = &config
+	}
+}
+
+func (config StateReportConfig) withDefaults() StateReportConfig {
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+
+	if config.MinInterval <= 0 {
+		config.MinInterval = DefaultStateReportInterval
+	}
+
+	if config.Backlog <= 0 {
+		config.Backlog = DefaultStateReportBacklog
+	}
+
+	return config
+}
+
+// stateReporter watches the state of a connection and reports its
+// transitions.
+type stateReporter struct {
+	config StateReportConfig
+	conn   *grpc.ClientConn
+	api    authorizeApi.AuthorizeClient
+
+	pending  []connectivity.State
+	lastSent time.Time
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func startStateReporter(conn *grpc.ClientConn, api authorizeApi.AuthorizeClient, config StateReportConfig) *stateReporter {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &stateReporter{
+		config: config.withDefaults(),
+		conn:   conn,
+		api:    api,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go r.run(ctx)
+
+	return r
+}
+
+// Close stops watching the connection. Transitions not reported yet are
+// dropped.
+func (r *stateReporter) Close() error {
+	r.closeOnce.Do(r.cancel)
+	<-r.done
+
+	return nil
+}
+
+func (r *stateReporter) run(ctx context.Context) {
+	defer close(r.done)
+
+	state := r.conn.GetState()
+	r.record(state)
+
+	for {
+		var wait time.Duration
+
+		switch {
+		case len(r.pending) == 0:
+		case state == connectivity.Ready:
+			wait = r.flush(ctx)
+		case state == connectivity.Idle:
+			r.conn.Connect()
+		}
+
+		if !r.waitForStateChange(ctx, state, wait) {
+			if ctx.Err() != nil {
+				return
+			}
+
+			continue
+		}
+
+		state = r.conn.GetState()
+		r.record(state)
+	}
+}
+
+// waitForStateChange waits for the state to change from state, at most for
+// wait if it is positive.
+func (r *stateReporter) waitForStateChange(ctx context.Context, state connectivity.State, wait time.Duration) bool {
+	if wait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, wait)
+		defer cancel()
+	}
+
+	return r.conn.WaitForStateChange(ctx, state)
+}
+
+func (r *stateReporter) record(state connectivity.State) {
+	if n := len(r.pending); n > 0 && r.pending[n-1] == state {
+		return
+	}
+
+	r.pending = append(r.pending, state)
+	if len(r.pending) > r.config.Backlog {
+		r.pending = r.pending[len(r.pending)-r.config.Backlog:]
+	}
+}
+
+// flush reports the pending transitions, oldest first, until one fails or
+// the rate limit is reached. It returns how long to wait before flushing
+// again, or zero when there is nothing to retry.
+func (r *stateReporter) flush(ctx context.Context) time.Duration {
+	for len(r.pending) > 0 {
+		if wait := time.Until(r.lastSent.Add(r.config.MinInterval)); wait > 0 {
+			return wait
+		}
+
+		r.lastSent = time.Now()
+		if err := r.report(ctx, r.pending[0]); err != nil {
+			return r.config.MinInterval
+		}
+
+		r.pending = r.pending[1:]
+	}
+
+	return 0
+}
+
+func (r *stateReporter) report(ctx context.Context, state connectivity.State) error {
+	ctx, cancel := context.WithTimeout(ctx, stateReportTimeout)
+	defer cancel()
+
+	_, err := r.api.LogClientState(ctx, &authorizeApi.LogClientStateInput{
+		State:    state.String(),
+		Hostname: r.config.Hostname,
+	})
+
+	return err
+}
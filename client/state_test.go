@@ -0,0 +1,99 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+)
+
+func stateReportingClient(t *testing.T, server *mock.StatefulAuthorizeServer, config authorize.StateReportConfig) authorize.AuthorizeClient {
+	c, err := authorize.New(context.Background(), net.JoinHostPort(server.HostPort()),
+		authorize.WithInsecure(),
+		authorize.WithStateReporting(config),
+		authorize.WithDialOptions(grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.Config{BaseDelay: 10 * time.Millisecond, Multiplier: 1.6, MaxDelay: 50 * time.Millisecond},
+			MinConnectTimeout: time.Second,
+		})),
+	)
+	require.NoError(t, err)
+
+	return c
+}
+
+func reportedStates(server *mock.StatefulAuthorizeServer) []string {
+	var states []string
+	for _, state := range server.ClientStates() {
+		states = append(states, state.GetState())
+	}
+
+	return states
+}
+
+func Test_StateReporting_AcrossRestart(t *testing.T) {
+	server, err := mock.NewStatefulServer()
+	require.NoError(t, err)
+	defer server.Stop(t)
+
+	c := stateReportingClient(t, server, authorize.StateReportConfig{Hostname: "test-host", MinInterval: time.Millisecond})
+	defer c.Close()
+
+	require.Eventually(t, func() bool {
+		return slices.Equal(reportedStates(server), []string{"IDLE", "CONNECTING", "READY"})
+	}, 5*time.Second, 10*time.Millisecond)
+
+	server.Restart(t, 100*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		states := reportedStates(server)
+		return len(states) > 4 && states[len(states)-1] == "READY"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Short-lived states may be skipped, as the state is read after it
+	// changed.
+	states := reportedStates(server)
+	assert.NotContains(t, states[3:len(states)-1], "READY", "Transitions while the server was down are reported once it is back")
+
+	for _, state := range server.ClientStates() {
+		assert.Equal(t, "test-host", state.GetHostname())
+	}
+}
+
+func Test_StateReporting_RateLimited(t *testing.T) {
+	server, err := mock.NewStatefulServer()
+	require.NoError(t, err)
+	defer server.Stop(t)
+
+	c := stateReportingClient(t, server, authorize.StateReportConfig{MinInterval: time.Hour})
+
+	require.Eventually(t, func() bool {
+		return len(server.ClientStates()) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, c.DeepPing(context.Background()))
+	require.NoError(t, c.Close())
+
+	assert.Equal(t, []string{"IDLE"}, reportedStates(server), "Later transitions wait for the rate limit")
+}
+
+func Test_StateReporting_Disabled(t *testing.T) {
+	server, err := mock.NewStatefulServer()
+	require.NoError(t, err)
+	defer server.Stop(t)
+
+	c := stateReportingClient(t, server, authorize.StateReportConfig{MinInterval: time.Millisecond, Disabled: true})
+
+	require.NoError(t, c.DeepPing(context.Background()))
+	require.NoError(t, c.Close())
+
+	assert.Empty(t, server.ClientStates())
+}
@@ -0,0 +1,258 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync/atomic"
+
+	authorizeApi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// tracerName is the name of the tracer creating the spans of the client.
+const tracerName = "github.com/SKF/go-enlight-authorizer/client"
+
+// Attributes of the spans created for every call.
+const (
+	AttributeUserID         = attribute.Key("authorize.user_id")
+	AttributeAction         = attribute.Key("authorize.action")
+	AttributeResourceType   = attribute.Key("authorize.resource.type")
+	AttributeResourceID     = attribute.Key("authorize.resource.id")
+	AttributeResourceCount  = attribute.Key("authorize.resource.count")
+	AttributeDecision       = attribute.Key("authorize.decision")
+	AttributeReason         = attribute.Key("authorize.reason")
+	AttributeAllowedCount   = attribute.Key("authorize.allowed.count")
+	AttributeAttempts       = attribute.Key("authorize.attempts")
+	attributeRPCSystem      = attribute.Key("rpc.system")
+	attributeRPCService     = attribute.Key("rpc.service")
+	attributeRPCMethod      = attribute.Key("rpc.method")
+	attributeGRPCStatusCode = attribute.Key("rpc.grpc.status_code")
+)
+
+// TracingOption configures the tracing enabled by WithTracing.
+type TracingOption func(*tracing)
+
+type tracing struct {
+	provider    trace.TracerProvider
+	propagator  propagation.TextMapPropagator
+	hashUserIDs bool
+	salt        string
+}
+
+// WithTracerProvider sets the provider of the tracer, the global provider by
+// default.
+func WithTracerProvider(provider trace.TracerProvider) TracingOption {
+	return func(t *tracing) {
+		t.provider = provider
+	}
+}
+
+// WithPropagator sets the propagator injecting the trace context into the
+// metadata of every call, the global propagator by default.
+func WithPropagator(propagator propagation.TextMapPropagator) TracingOption {
+	return func(t *tracing) {
+		t.propagator = propagator
+	}
+}
+
+// WithHashedUserIDs records the SHA-256 hash of the user ID, prefixed with
+// salt, rather than the user ID itself.
+func WithHashedUserIDs(salt string) TracingOption {
+	return func(t *tracing) {
+		t.hashUserIDs = true
+		t.salt = salt
+	}
+}
+
+// WithTracing creates an OpenTelemetry span for every call, including the
+// user, action and resource of the request and the decision, and propagates
+// the trace context to the service.
+func WithTracing(opts ...TracingOption) Option {
+	t := &tracing{
+		provider:   otel.GetTracerProvider(),
+		propagator: otel.GetTextMapPropagator(),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return func(o *options) {
+		o.tracing = t
+	}
+}
+
+func (t *tracing) interceptor() grpc.UnaryClientInterceptor {
+	tracer := t.provider.Tracer(tracerName)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		service, name := splitMethod(method)
+
+		ctx, span := tracer.Start(ctx, strings.TrimPrefix(method, "/"),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attributeRPCSystem.String("grpc"),
+				attributeRPCService.String(service),
+				attributeRPCMethod.String(name),
+			),
+			trace.WithAttributes(t.requestAttributes(req)...),
+		)
+		defer span.End()
+
+		md, _ := metadata.FromOutgoingContext(ctx)
+		md = md.Copy()
+		t.propagator.Inject(ctx, metadataCarrier(md))
+		ctx = metadata.NewOutgoingContext(ctx, md)
+
+		attempts := new(atomic.Int64)
+		ctx = context.WithValue(ctx, attemptsKey{}, attempts)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		span.SetAttributes(
+			AttributeAttempts.Int64(attempts.Load()),
+			attributeGRPCStatusCode.Int64(int64(status.Code(err))),
+		)
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+
+			return err
+		}
+
+		span.SetAttributes(replyAttributes(reply)...)
+
+		return nil
+	}
+}
+
+func (t *tracing) requestAttributes(req interface{}) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+
+	if r, ok := req.(interface{ GetUserId() string }); ok && r.GetUserId() != "" {
+		attrs = append(attrs, AttributeUserID.String(t.userID(r.GetUserId())))
+	}
+
+	if r, ok := req.(interface{ GetAction() string }); ok && r.GetAction() != "" {
+		attrs = append(attrs, AttributeAction.String(r.GetAction()))
+	}
+
+	if r, ok := req.(interface{ GetResource() *common.Origin }); ok && r.GetResource() != nil {
+		attrs = append(attrs,
+			AttributeResourceType.String(r.GetResource().GetType()),
+			AttributeResourceID.String(r.GetResource().GetId()),
+		)
+	}
+
+	if r, ok := req.(interface{ GetResources() []*common.Origin }); ok {
+		attrs = append(attrs, AttributeResourceCount.Int(len(r.GetResources())))
+	}
+
+	return attrs
+}
+
+func (t *tracing) userID(userID string) string {
+	if !t.hashUserIDs {
+		return userID
+	}
+
+	hash := sha256.Sum256([]byte(t.salt + userID))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func replyAttributes(reply interface{}) []attribute.KeyValue {
+	if r, ok := reply.(*authorizeApi.IsAuthorizedWithReasonOutput); ok {
+		return []attribute.KeyValue{AttributeDecision.Bool(r.GetOk()), AttributeReason.String(r.GetReason())}
+	}
+
+	if r, ok := reply.(interface{ GetOk() bool }); ok {
+		return []attribute.KeyValue{AttributeDecision.Bool(r.GetOk())}
+	}
+
+	if r, ok := reply.(*authorizeApi.IsAuthorizedBulkOutput); ok {
+		allowed := 0
+		for _, response := range r.GetResponses() {
+			if response.GetOk() {
+				allowed++
+			}
+		}
+
+		return []attribute.KeyValue{AttributeAllowedCount.Int(allowed)}
+	}
+
+	return nil
+}
+
+// splitMethod splits a full method name, /package.Service/Method, into the
+// service and method names.
+func splitMethod(method string) (string, string) {
+	method = strings.TrimPrefix(method, "/")
+	if i := strings.LastIndex(method, "/"); i >= 0 {
+		return method[:i], method[i+1:]
+	}
+
+	return "", method
+}
+
+// metadataCarrier injects the trace context into the metadata of a call.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+type attemptsKey struct{}
+
+// attemptCounter is a stats handler counting the attempts of every call,
+// including the retries made according to the retry policy.
+type attemptCounter struct{}
+
+func (attemptCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (attemptCounter) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	if _, ok := s.(*stats.Begin); !ok {
+		return
+	}
+
+	if attempts, ok := ctx.Value(attemptsKey{}).(*atomic.Int64); ok {
+		attempts.Add(1)
+	}
+}
+
+func (attemptCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (attemptCounter) HandleConn(context.Context, stats.ConnStats) {}
@@ -0,0 +1,140 @@
+package client_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	authMock "github.com/SKF/go-enlight-authorizer/mock"
+	grpcapi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func tracingClient(t *testing.T, target string, opts ...authorize.TracingOption) (authorize.AuthorizeClient, *tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	opts = append([]authorize.TracingOption{
+		authorize.WithTracerProvider(provider),
+		authorize.WithPropagator(propagation.TraceContext{}),
+	}, opts...)
+
+	c, err := authorize.New(context.Background(), target, authorize.WithInsecure(), authorize.WithTracing(opts...))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { c.Close() })
+
+	return c, exporter, provider
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+
+	return attrs
+}
+
+func Test_Tracing(t *testing.T) {
+	traceparents := make(chan []string, 1)
+	server, err := authMock.NewStatefulServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		traceparents <- md.Get("traceparent")
+
+		return handler(ctx, req)
+	}))
+	require.NoError(t, err)
+	defer server.Stop(t)
+
+	site := &common.Origin{Id: "site", Type: "site", Provider: "hierarchy"}
+	require.NoError(t, server.Store.AddResource(context.Background(), site))
+
+	c, exporter, provider := tracingClient(t, net.JoinHostPort(server.HostPort()), authorize.WithHashedUserIDs("salt"))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "handler")
+	ok, err := c.IsAuthorized(ctx, "user", "read", site)
+	parent.End()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	span := spans[0]
+	assert.Equal(t, "grpcapi.Authorize/IsAuthorized", span.Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+
+	hash := sha256.Sum256([]byte("saltuser"))
+	attrs := spanAttributes(span)
+	assert.Equal(t, "IsAuthorized", attrs["rpc.method"].AsString())
+	assert.Equal(t, hex.EncodeToString(hash[:]), attrs[authorize.AttributeUserID].AsString())
+	assert.Equal(t, "read", attrs[authorize.AttributeAction].AsString())
+	assert.Equal(t, "site", attrs[authorize.AttributeResourceType].AsString())
+	assert.Equal(t, "site", attrs[authorize.AttributeResourceID].AsString())
+	assert.False(t, attrs[authorize.AttributeDecision].AsBool())
+	assert.Equal(t, int64(1), attrs[authorize.AttributeAttempts].AsInt64())
+
+	select {
+	case traceparent := <-traceparents:
+		require.Len(t, traceparent, 1)
+		assert.Contains(t, traceparent[0], span.SpanContext.TraceID().String(), "The trace context is propagated to the service")
+		assert.Contains(t, traceparent[0], span.SpanContext.SpanID().String())
+	case <-time.After(time.Second):
+		t.Fatal("no call reached the server")
+	}
+}
+
+func Test_Tracing_RetriesAndErrors(t *testing.T) {
+	server, err := authMock.NewServer()
+	require.NoError(t, err)
+
+	server.On("IsAuthorizedBulk", mock.Anything, mock.Anything).
+		Return((*grpcapi.IsAuthorizedBulkOutput)(nil), status.Error(codes.Unavailable, "failure")).Once()
+	server.On("IsAuthorizedBulk", mock.Anything, mock.Anything).
+		Return(&grpcapi.IsAuthorizedBulkOutput{Responses: []*grpcapi.IsAuthorizedOutItem{{Ok: true}, {Ok: false}}}, nil).Once()
+	server.On("GetResource", mock.Anything, mock.Anything).
+		Return((*grpcapi.GetResourceOutput)(nil), status.Error(codes.NotFound, "no such resource"))
+
+	c, exporter, _ := tracingClient(t, net.JoinHostPort(server.HostPort()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, _, err = c.IsAuthorizedBulk(ctx, "user", "read", []*common.Origin{{Id: "a"}, {Id: "b"}})
+	require.NoError(t, err)
+
+	_, err = c.GetResource(ctx, "missing", "site")
+	require.Error(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	attrs := spanAttributes(spans[0])
+	assert.Equal(t, "user", attrs[authorize.AttributeUserID].AsString())
+	assert.Equal(t, int64(2), attrs[authorize.AttributeResourceCount].AsInt64())
+	assert.Equal(t, int64(1), attrs[authorize.AttributeAllowedCount].AsInt64())
+	assert.Equal(t, int64(2), attrs[authorize.AttributeAttempts].AsInt64(), "The retry is counted")
+
+	attrs = spanAttributes(spans[1])
+	assert.Equal(t, otelcodes.Error, spans[1].Status.Code)
+	assert.Equal(t, "no such resource", spans[1].Status.Description)
+	assert.Equal(t, int64(codes.NotFound), attrs["rpc.grpc.status_code"].AsInt64())
+}
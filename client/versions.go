@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+)
+
+// VersionedCredentials is implemented by the transport credentials created
+// by NewAutoRefreshingTransportCredentials.
+type VersionedCredentials interface {
+	// ActiveVersion returns the version stage and ID of the secret the
+	// certificates in use were read from, if the credentials fetcher reports
+	// them.
+	ActiveVersion() (versionStage, versionID string)
+}
+
+var _ VersionedCredentials = &autoRefreshingTransportCredentials{}
+
+// WithVersionFallback reads the certificates from the given version stages,
+// in order of preference, which defaults to the current, previous and pending
+// stage. When the certificates are rejected during a handshake, e.g. as the
+// server doesn't trust a freshly rotated certificate yet, the next stage is
+// used for the following handshakes until the certificates are reloaded,
+// which starts over from the preferred stage. It requires a credentials fetcher
+// implementing credentialsmanager.StagedFetcher.
+func WithVersionFallback(stages ...string) CredentialsOption {
+	if len(stages) == 0 {
+		stages = []string{
+			credentialsmanager.VersionStageCurrent,
+			credentialsmanager.VersionStagePrevious,
+			credentialsmanager.VersionStagePending,
+		}
+	}
+
+	return func(c *autoRefreshingTransportCredentials) {
+		c.stages = stages
+	}
+}
+
+func (c *autoRefreshingTransportCredentials) ActiveVersion() (versionStage, versionID string) {
+	state := c.state.Load()
+
+	return state.versionStage, state.versionID
+}
+
+// fallBack switches to the next version stage which can be loaded, unless the
+// rejected certificates have already been replaced.
+func (c *autoRefreshingTransportCredentials) fallBack(ctx context.Context, rejected *credentialsState) {
+	if err := c.acquire(ctx); err != nil {
+		return
+	}
+	defer c.release()
+
+	if c.state.Load() != rejected {
+		return
+	}
+
+	for i := 1; i < len(c.stages); i++ {
+		stageIndex := (rejected.stageIndex + i) % len(c.stages)
+
+		if err := c.loadStage(ctx, stageIndex); err != nil {
+			continue
+		}
+
+		c.notify(c.state.Load().event(nil, 0))
+
+		return
+	}
+}
+
+// isCertificateRejection reports whether a handshake failed as either side
+// didn't accept the certificate of the other.
+func isCertificateRejection(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	if errors.As(err, &verificationErr) {
+		return true
+	}
+
+	var alert tls.AlertError
+	if !errors.As(err, &alert) {
+		return false
+	}
+
+	switch alert {
+	case 42, // bad_certificate
+		43,  // unsupported_certificate
+		44,  // certificate_revoked
+		45,  // certificate_expired
+		46,  // certificate_unknown
+		48,  // unknown_ca
+		116: // certificate_required
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,168 @@
+package client_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	"github.com/SKF/go-enlight-authorizer/pkitest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/credentials"
+)
+
+type stagedFetcher struct {
+	mu     sync.Mutex
+	stages map[string]credentialsmanager.DataStore
+}
+
+func (f *stagedFetcher) GetDataStore(ctx context.Context, secretsName string) (*credentialsmanager.DataStore, error) {
+	return f.GetDataStoreVersion(ctx, secretsName, credentialsmanager.VersionStageCurrent)
+}
+
+func (f *stagedFetcher) GetDataStoreVersion(ctx context.Context, secretsName, versionStage string) (*credentialsmanager.DataStore, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ds, ok := f.stages[versionStage]
+	if !ok {
+		return nil, assert.AnError
+	}
+
+	ds.VersionStage = versionStage
+	return &ds, nil
+}
+
+// untrustedDatastore returns valid credentials issued by a CA which didn't
+// issue the certificates of datastoreValidFor.
+func untrustedDatastore(t *testing.T) credentialsmanager.DataStore {
+	ca, err := pkitest.NewCA(pkitest.WithKeyType(pkitest.RSA))
+	require.NoError(t, err)
+
+	ds, err := ca.Issue(pkitest.WithValidFor(DistantFuture), pkitest.WithDNSNames("localhost"))
+	require.NoError(t, err)
+
+	return ds
+}
+
+// connPair returns both ends of a loopback TCP connection. Unlike the ends of
+// net.Pipe they are buffered, so that an alert sent by a side rejecting a
+// certificate doesn't deadlock with the other side writing.
+func connPair(t *testing.T) (server, client net.Conn) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	client, err = net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+
+	server, err = lis.Accept()
+	require.NoError(t, err)
+
+	return server, client
+}
+
+// handshake performs a handshake against a TLS server using serverDataStore.
+func handshake(t *testing.T, creds credentials.TransportCredentials, serverDataStore credentialsmanager.DataStore) error {
+	serverCert, err := tls.X509KeyPair(serverDataStore.Crt, serverDataStore.Key)
+	require.NoError(t, err)
+
+	serverConn, clientConn := connPair(t)
+	defer clientConn.Close()
+
+	go func() {
+		defer serverConn.Close()
+		_ = tls.Server(serverConn, &tls.Config{Certificates: []tls.Certificate{serverCert}, NextProtos: []string{"h2"}}).Handshake()
+	}()
+
+	_, _, err = creds.ClientHandshake(context.Background(), "localhost", clientConn)
+
+	return err
+}
+
+func Test_VersionFallback(t *testing.T) {
+	good := datastoreValidFor(t, DistantFuture)
+	good.VersionID = "v1"
+
+	rotated := untrustedDatastore(t)
+	rotated.VersionID = "v2"
+
+	cf := &stagedFetcher{stages: map[string]credentialsmanager.DataStore{
+		credentialsmanager.VersionStageCurrent:  rotated,
+		credentialsmanager.VersionStagePrevious: good,
+	}}
+
+	var events []client.RotationEvent
+	creds, err := client.NewAutoRefreshingTransportCredentials(context.Background(), cf, "secret", "localhost",
+		client.WithVersionFallback(),
+		client.WithBackgroundRefresh(client.RefreshConfig{
+			OnRotate: func(event client.RotationEvent) { events = append(events, event) },
+		}),
+	)
+	require.NoError(t, err)
+	defer creds.(io.Closer).Close()
+
+	versioned := creds.(client.VersionedCredentials)
+
+	stage, id := versioned.ActiveVersion()
+	assert.Equal(t, credentialsmanager.VersionStageCurrent, stage)
+	assert.Equal(t, "v2", id)
+
+	require.Error(t, handshake(t, creds, good), "The server isn't trusted by the current version")
+
+	stage, id = versioned.ActiveVersion()
+	assert.Equal(t, credentialsmanager.VersionStagePrevious, stage)
+	assert.Equal(t, "v1", id)
+
+	require.Len(t, events, 1)
+	assert.Equal(t, credentialsmanager.VersionStagePrevious, events[0].VersionStage)
+
+	require.NoError(t, handshake(t, creds, good), "The previous version is used for the following handshakes")
+}
+
+func Test_VersionFallback_ReturnsToCurrentStage(t *testing.T) {
+	good := datastoreValidFor(t, DistantFuture)
+	good.VersionID = "v1"
+
+	rotated := untrustedDatastore(t)
+	rotated.VersionID = "v2"
+
+	cf := &pkitest.Fetcher{}
+	cf.RotateStage(credentialsmanager.VersionStageCurrent, rotated)
+	cf.RotateStage(credentialsmanager.VersionStagePrevious, good)
+
+	creds, err := client.NewAutoRefreshingTransportCredentials(context.Background(), cf, "secret", "localhost",
+		client.WithVersionFallback(),
+	)
+	require.NoError(t, err)
+
+	versioned := creds.(client.VersionedCredentials)
+
+	require.Error(t, handshake(t, creds, good), "The server isn't trusted by the current version")
+
+	stage, _ := versioned.ActiveVersion()
+	require.Equal(t, credentialsmanager.VersionStagePrevious, stage)
+
+	fixed := datastoreValidFor(t, DistantFuture)
+	fixed.VersionID = "v3"
+	cf.Rotate(fixed)
+
+	require.NoError(t, handshake(t, creds, good))
+
+	stage, id := versioned.ActiveVersion()
+	assert.Equal(t, credentialsmanager.VersionStageCurrent, stage, "Reloads start over from the current version")
+	assert.Equal(t, "v3", id)
+}
+
+func Test_VersionFallback_RequiresStagedFetcher(t *testing.T) {
+	_, err := client.NewAutoRefreshingTransportCredentials(context.Background(), &rotatingFetcher{}, "secret", "localhost",
+		client.WithVersionFallback(),
+	)
+	assert.Error(t, err)
+}
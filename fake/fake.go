@@ -0,0 +1,834 @@
+// Package fake provides a stateful in-memory implementation of
+// client.AuthorizeClient for use in tests.
+//
+// Resources are identified by their id and type. Users are granted actions on
+// resources, either directly with ApplyUserAction or through the actions of a
+// role with ApplyRolesForUserOnResources, and a grant on a resource is
+// inherited by every descendant of that resource.
+package fake
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	grpcapi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+)
+
+type resourceKey struct {
+	id, originType string
+}
+
+func keyOf(resource *common.Origin) resourceKey {
+	return resourceKey{id: resource.GetId(), originType: resource.GetType()}
+}
+
+type grant struct {
+	action   string
+	resource resourceKey
+}
+
+type endpoint struct {
+	api, method, endpoint string
+}
+
+type Client struct {
+	mu        sync.RWMutex
+	resources map[resourceKey]*common.Origin
+	parents   map[resourceKey]map[resourceKey]struct{}
+	children  map[resourceKey]map[resourceKey]struct{}
+	actions   map[string]*grpcapi.Action
+	roles     map[string]*grpcapi.UserRole
+	grants    map[string]map[grant]struct{}
+	endpoints map[endpoint]string
+}
+
+var _ authorize.AuthorizeClient = &Client{}
+
+func New() *Client {
+	return &Client{
+		resources: make(map[resourceKey]*common.Origin),
+		parents:   make(map[resourceKey]map[resourceKey]struct{}),
+		children:  make(map[resourceKey]map[resourceKey]struct{}),
+		actions:   make(map[string]*grpcapi.Action),
+		roles:     make(map[string]*grpcapi.UserRole),
+		grants:    make(map[string]map[grant]struct{}),
+		endpoints: make(map[endpoint]string),
+	}
+}
+
+// AddEndpoint makes IsAuthorizedByEndpoint authorize a user for the endpoint
+// if the user has been granted the action on any resource.
+func (c *Client) AddEndpoint(api, method, path, action string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.endpoints[endpoint{api: api, method: method, endpoint: path}] = action
+}
+
+func (c *Client) Dial(ctx context.Context, host, port string, opts ...grpc.DialOption) error {
+	return nil
+}
+
+func (c *Client) DialUsingCredentialsManager(ctx context.Context, cf credentialsmanager.CredentialsFetcher, host, port, secretKey string, opts ...grpc.DialOption) error {
+	return nil
+}
+
+func (c *Client) SetRequestTimeout(d time.Duration) {}
+
+func (c *Client) DeepPing(ctx context.Context) error {
+	return nil
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) IsAuthorized(ctx context.Context, userID, action string, resource *common.Origin) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ok, _ := c.isAuthorized(userID, action, keyOf(resource))
+
+	return ok, nil
+}
+
+func (c *Client) IsAuthorizedWithReason(ctx context.Context, userID, action string, resource *common.Origin) (bool, string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ok, reason := c.isAuthorized(userID, action, keyOf(resource))
+
+	return ok, reason, nil
+}
+
+func (c *Client) IsAuthorizedBulk(ctx context.Context, userID, action string, resources []*common.Origin) ([]*common.Origin, []bool, error) {
+	if len(resources) > authorize.REQUEST_LENGTH_LIMIT {
+		return nil, nil, errorf("IsAuthorizedBulk", authorize.ErrLimitExceeded, "request length limit exceeded. max: %d actual: %d", authorize.REQUEST_LENGTH_LIMIT, len(resources))
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	echoed := make([]*common.Origin, len(resources))
+	oks := make([]bool, len(resources))
+
+	for i, resource := range resources {
+		echoed[i] = clone(resource)
+		oks[i], _ = c.isAuthorized(userID, action, keyOf(resource))
+	}
+
+	return echoed, oks, nil
+}
+
+func (c *Client) IsAuthorizedByEndpoint(ctx context.Context, api, method, path, userID string) (bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	action, ok := c.endpoints[endpoint{api: api, method: method, endpoint: path}]
+	if !ok {
+		return false, nil
+	}
+
+	for g := range c.grants[userID] {
+		if g.action == action {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (c *Client) AddResource(ctx context.Context, resource *common.Origin) error {
+	return c.addResources("AddResource", []*common.Origin{resource})
+}
+
+func (c *Client) AddResources(ctx context.Context, resources []*common.Origin) error {
+	return c.addResources("AddResources", resources)
+}
+
+func (c *Client) addResources(method string, resources []*common.Origin) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, resource := range resources {
+		if resource.GetId() == "" || resource.GetType() == "" {
+			return authorize.NewError(method, authorize.ErrInvalidArgument, "resource id and type are required")
+		}
+
+		if _, ok := c.resources[keyOf(resource)]; ok {
+			return errorf(method, authorize.ErrAlreadyExists, "resource %s/%s already exists", resource.GetType(), resource.GetId())
+		}
+	}
+
+	for _, resource := range resources {
+		c.resources[keyOf(resource)] = clone(resource)
+	}
+
+	return nil
+}
+
+func (c *Client) GetResource(ctx context.Context, id, originType string) (*common.Origin, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	resource, ok := c.resources[resourceKey{id: id, originType: originType}]
+	if !ok {
+		return nil, errorf("GetResource", authorize.ErrNotFound, "resource %s/%s not found", originType, id)
+	}
+
+	return clone(resource), nil
+}
+
+func (c *Client) RemoveResource(ctx context.Context, resource *common.Origin) error {
+	return c.removeResources("RemoveResource", []*common.Origin{resource})
+}
+
+func (c *Client) RemoveResources(ctx context.Context, resources []*common.Origin) error {
+	return c.removeResources("RemoveResources", resources)
+}
+
+func (c *Client) removeResources(method string, resources []*common.Origin) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, resource := range resources {
+		if err := c.requireResource(method, resource); err != nil {
+			return err
+		}
+	}
+
+	for _, resource := range resources {
+		key := keyOf(resource)
+
+		for parent := range c.parents[key] {
+			delete(c.children[parent], key)
+		}
+
+		for child := range c.children[key] {
+			delete(c.parents[child], key)
+		}
+
+		for _, grants := range c.grants {
+			for g := range grants {
+				if g.resource == key {
+					delete(grants, g)
+				}
+			}
+		}
+
+		delete(c.parents, key)
+		delete(c.children, key)
+		delete(c.resources, key)
+	}
+
+	return nil
+}
+
+// GetResourcesWithActionsAccess returns the resources of the given type which
+// the user identified by resource has been granted any of the actions on.
+func (c *Client) GetResourcesWithActionsAccess(ctx context.Context, actions []string, resourceType string, resource *common.Origin) ([]*common.Origin, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.filterResources(func(key resourceKey) bool {
+		if resourceType != "" && key.originType != resourceType {
+			return false
+		}
+
+		for _, action := range actions {
+			if ok, _ := c.isAuthorized(resource.GetId(), action, key); ok {
+				return true
+			}
+		}
+
+		return false
+	}), nil
+}
+
+func (c *Client) GetResourcesByUserAction(ctx context.Context, userID, actionName, resourceType string) ([]*common.Origin, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.filterResources(func(key resourceKey) bool {
+		if resourceType != "" && key.originType != resourceType {
+			return false
+		}
+
+		ok, _ := c.isAuthorized(userID, actionName, key)
+
+		return ok
+	}), nil
+}
+
+func (c *Client) GetResourcesByType(ctx context.Context, resourceType string) ([]*common.Origin, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.filterResources(func(key resourceKey) bool {
+		return key.originType == resourceType
+	}), nil
+}
+
+// GetResourcesByOriginAndType returns the descendants of resource with the
+// given type, at most depth levels down. A depth of zero or less is unlimited.
+func (c *Client) GetResourcesByOriginAndType(ctx context.Context, resource *common.Origin, resourceType string, depth int32) ([]*common.Origin, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.requireResource("GetResourcesByOriginAndType", resource); err != nil {
+		return nil, err
+	}
+
+	descendants := c.walk(keyOf(resource), c.children, int(depth))
+
+	return c.filterResources(func(key resourceKey) bool {
+		_, ok := descendants[key]
+		return ok && (resourceType == "" || key.originType == resourceType)
+	}), nil
+}
+
+// GetResourceParents returns the direct parents of resource, optionally
+// filtered by type.
+func (c *Client) GetResourceParents(ctx context.Context, resource *common.Origin, parentOriginType string) ([]*common.Origin, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.requireResource("GetResourceParents", resource); err != nil {
+		return nil, err
+	}
+
+	parents := c.parents[keyOf(resource)]
+
+	return c.filterResources(func(key resourceKey) bool {
+		_, ok := parents[key]
+		return ok && (parentOriginType == "" || key.originType == parentOriginType)
+	}), nil
+}
+
+// GetResourceChildren returns the direct children of resource, optionally
+// filtered by type.
+func (c *Client) GetResourceChildren(ctx context.Context, resource *common.Origin, childOriginType string) ([]*common.Origin, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.requireResource("GetResourceChildren", resource); err != nil {
+		return nil, err
+	}
+
+	children := c.children[keyOf(resource)]
+
+	return c.filterResources(func(key resourceKey) bool {
+		_, ok := children[key]
+		return ok && (childOriginType == "" || key.originType == childOriginType)
+	}), nil
+}
+
+func (c *Client) GetUserIDsWithAccessToResource(ctx context.Context, resource *common.Origin) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.requireResource("GetUserIDsWithAccessToResource", resource); err != nil {
+		return nil, err
+	}
+
+	lineage := c.lineage(keyOf(resource))
+
+	userIDs := []string{}
+
+	for userID, grants := range c.grants {
+		for g := range grants {
+			if _, ok := lineage[g.resource]; ok {
+				userIDs = append(userIDs, userID)
+				break
+			}
+		}
+	}
+
+	slices.Sort(userIDs)
+
+	return userIDs, nil
+}
+
+func (c *Client) AddResourceRelation(ctx context.Context, resource, parent *common.Origin) error {
+	return c.addResourceRelations("AddResourceRelation", []*grpcapi.AddResourceRelationInput{{Resource: resource, Parent: parent}})
+}
+
+func (c *Client) AddResourceRelations(ctx context.Context, resources *grpcapi.AddResourceRelationsInput) error {
+	return c.addResourceRelations("AddResourceRelations", resources.GetRelation())
+}
+
+func (c *Client) addResourceRelations(method string, relations []*grpcapi.AddResourceRelationInput) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, relation := range relations {
+		if err := c.requireResource(method, relation.GetResource()); err != nil {
+			return err
+		}
+
+		if err := c.requireResource(method, relation.GetParent()); err != nil {
+			return err
+		}
+
+		resource, parent := keyOf(relation.GetResource()), keyOf(relation.GetParent())
+
+		if _, ok := c.parents[resource][parent]; ok {
+			return errorf(method, authorize.ErrAlreadyExists, "relation %s/%s -> %s/%s already exists", resource.originType, resource.id, parent.originType, parent.id)
+		}
+
+		if _, ok := c.lineage(parent)[resource]; ok {
+			return errorf(method, authorize.ErrInvalidArgument, "relation %s/%s -> %s/%s would create a cycle", resource.originType, resource.id, parent.originType, parent.id)
+		}
+	}
+
+	for _, relation := range relations {
+		resource, parent := keyOf(relation.GetResource()), keyOf(relation.GetParent())
+
+		if c.parents[resource] == nil {
+			c.parents[resource] = make(map[resourceKey]struct{})
+		}
+
+		if c.children[parent] == nil {
+			c.children[parent] = make(map[resourceKey]struct{})
+		}
+
+		c.parents[resource][parent] = struct{}{}
+		c.children[parent][resource] = struct{}{}
+	}
+
+	return nil
+}
+
+func (c *Client) RemoveResourceRelation(ctx context.Context, resource, parent *common.Origin) error {
+	return c.removeResourceRelations("RemoveResourceRelation", []*grpcapi.RemoveResourceRelationInput{{Resource: resource, Parent: parent}})
+}
+
+func (c *Client) RemoveResourceRelations(ctx context.Context, resources *grpcapi.RemoveResourceRelationsInput) error {
+	return c.removeResourceRelations("RemoveResourceRelations", resources.GetRelation())
+}
+
+func (c *Client) removeResourceRelations(method string, relations []*grpcapi.RemoveResourceRelationInput) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, relation := range relations {
+		resource, parent := keyOf(relation.GetResource()), keyOf(relation.GetParent())
+
+		if _, ok := c.parents[resource][parent]; !ok {
+			return errorf(method, authorize.ErrNotFound, "relation %s/%s -> %s/%s not found", resource.originType, resource.id, parent.originType, parent.id)
+		}
+	}
+
+	for _, relation := range relations {
+		resource, parent := keyOf(relation.GetResource()), keyOf(relation.GetParent())
+
+		delete(c.parents[resource], parent)
+		delete(c.children[parent], resource)
+	}
+
+	return nil
+}
+
+func (c *Client) ApplyUserAction(ctx context.Context, userID, action string, resource *common.Origin) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if userID == "" {
+		return authorize.NewError("ApplyUserAction", authorize.ErrInvalidArgument, "user id is required")
+	}
+
+	if err := c.requireAction("ApplyUserAction", action); err != nil {
+		return err
+	}
+
+	if err := c.requireResource("ApplyUserAction", resource); err != nil {
+		return err
+	}
+
+	c.grant(userID, action, keyOf(resource))
+
+	return nil
+}
+
+func (c *Client) ApplyRolesForUserOnResources(ctx context.Context, userID string, roles []string, resources []*common.Origin) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if userID == "" {
+		return authorize.NewError("ApplyRolesForUserOnResources", authorize.ErrInvalidArgument, "user id is required")
+	}
+
+	for _, role := range roles {
+		if _, ok := c.roles[role]; !ok {
+			return errorf("ApplyRolesForUserOnResources", authorize.ErrNotFound, "role %s not found", role)
+		}
+	}
+
+	for _, resource := range resources {
+		if err := c.requireResource("ApplyRolesForUserOnResources", resource); err != nil {
+			return err
+		}
+	}
+
+	for _, role := range roles {
+		for _, action := range c.roles[role].GetActions() {
+			for _, resource := range resources {
+				c.grant(userID, action, keyOf(resource))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) RemoveUserAction(ctx context.Context, userID, action string, resource *common.Origin) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g := grant{action: action, resource: keyOf(resource)}
+	if _, ok := c.grants[userID][g]; !ok {
+		return errorf("RemoveUserAction", authorize.ErrNotFound, "action %s on %s/%s not granted to user %s", action, resource.GetType(), resource.GetId(), userID)
+	}
+
+	delete(c.grants[userID], g)
+
+	return nil
+}
+
+func (c *Client) GetActionsByUserRole(ctx context.Context, userRole string) ([]*grpcapi.Action, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	role, ok := c.roles[userRole]
+	if !ok {
+		return nil, errorf("GetActionsByUserRole", authorize.ErrNotFound, "role %s not found", userRole)
+	}
+
+	return c.actionsByName(role.GetActions()), nil
+}
+
+func (c *Client) GetResourcesAndActionsByUser(ctx context.Context, userID string) ([]*grpcapi.ActionResource, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.actionResources(userID, func(resourceKey) bool { return true }), nil
+}
+
+// GetResourcesAndActionsByUserAndResource returns the grants of the user on
+// the resource and on every ancestor of it.
+func (c *Client) GetResourcesAndActionsByUserAndResource(ctx context.Context, userID string, resource *common.Origin) ([]*grpcapi.ActionResource, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.requireResource("GetResourcesAndActionsByUserAndResource", resource); err != nil {
+		return nil, err
+	}
+
+	lineage := c.lineage(keyOf(resource))
+
+	return c.actionResources(userID, func(key resourceKey) bool {
+		_, ok := lineage[key]
+		return ok
+	}), nil
+}
+
+func (c *Client) AddAction(ctx context.Context, action *grpcapi.Action) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if action.GetName() == "" {
+		return authorize.NewError("AddAction", authorize.ErrInvalidArgument, "action name is required")
+	}
+
+	if _, ok := c.actions[action.GetName()]; ok {
+		return errorf("AddAction", authorize.ErrAlreadyExists, "action %s already exists", action.GetName())
+	}
+
+	c.actions[action.GetName()] = cloneAction(action)
+
+	return nil
+}
+
+func (c *Client) RemoveAction(ctx context.Context, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.requireAction("RemoveAction", name); err != nil {
+		return err
+	}
+
+	for _, grants := range c.grants {
+		for g := range grants {
+			if g.action == name {
+				delete(grants, g)
+			}
+		}
+	}
+
+	for _, role := range c.roles {
+		role.Actions = slices.DeleteFunc(role.Actions, func(action string) bool {
+			return action == name
+		})
+	}
+
+	delete(c.actions, name)
+
+	return nil
+}
+
+func (c *Client) GetAction(ctx context.Context, name string) (*grpcapi.Action, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if err := c.requireAction("GetAction", name); err != nil {
+		return nil, err
+	}
+
+	return cloneAction(c.actions[name]), nil
+}
+
+func (c *Client) GetAllActions(ctx context.Context) ([]*grpcapi.Action, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.actions))
+	for name := range c.actions {
+		names = append(names, name)
+	}
+
+	return c.actionsByName(names), nil
+}
+
+func (c *Client) GetUserActions(ctx context.Context, userID string) ([]*grpcapi.Action, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := []string{}
+	for g := range c.grants[userID] {
+		if !slices.Contains(names, g.action) {
+			names = append(names, g.action)
+		}
+	}
+
+	return c.actionsByName(names), nil
+}
+
+func (c *Client) AddUserRole(ctx context.Context, role *grpcapi.UserRole) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if role.GetName() == "" {
+		return authorize.NewError("AddUserRole", authorize.ErrInvalidArgument, "role name is required")
+	}
+
+	if _, ok := c.roles[role.GetName()]; ok {
+		return errorf("AddUserRole", authorize.ErrAlreadyExists, "role %s already exists", role.GetName())
+	}
+
+	for _, action := range role.GetActions() {
+		if err := c.requireAction("AddUserRole", action); err != nil {
+			return err
+		}
+	}
+
+	c.roles[role.GetName()] = &grpcapi.UserRole{
+		Name:    role.GetName(),
+		Actions: slices.Clone(role.GetActions()),
+	}
+
+	return nil
+}
+
+func (c *Client) GetUserRole(ctx context.Context, roleName string) (*grpcapi.UserRole, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	role, ok := c.roles[roleName]
+	if !ok {
+		return nil, errorf("GetUserRole", authorize.ErrNotFound, "role %s not found", roleName)
+	}
+
+	return &grpcapi.UserRole{
+		Name:    role.GetName(),
+		Actions: slices.Clone(role.GetActions()),
+	}, nil
+}
+
+func (c *Client) RemoveUserRole(ctx context.Context, roleName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.roles[roleName]; !ok {
+		return errorf("RemoveUserRole", authorize.ErrNotFound, "role %s not found", roleName)
+	}
+
+	delete(c.roles, roleName)
+
+	return nil
+}
+
+// isAuthorized must be called with c.mu held.
+func (c *Client) isAuthorized(userID, action string, resource resourceKey) (bool, string) {
+	if _, ok := c.resources[resource]; !ok {
+		return false, authorize.ReasonResourceNotFound
+	}
+
+	for key := range c.lineage(resource) {
+		if _, ok := c.grants[userID][grant{action: action, resource: key}]; ok {
+			return true, ""
+		}
+	}
+
+	return false, authorize.ReasonAccessDenied
+}
+
+// lineage returns the resource together with all of its ancestors.
+func (c *Client) lineage(resource resourceKey) map[resourceKey]struct{} {
+	lineage := c.walk(resource, c.parents, 0)
+	lineage[resource] = struct{}{}
+
+	return lineage
+}
+
+// walk returns every resource reachable from start by following edges, at
+// most depth steps away. A depth of zero or less is unlimited.
+func (c *Client) walk(start resourceKey, edges map[resourceKey]map[resourceKey]struct{}, depth int) map[resourceKey]struct{} {
+	visited := map[resourceKey]struct{}{}
+	frontier := []resourceKey{start}
+
+	for level := 1; len(frontier) > 0 && (depth <= 0 || level <= depth); level++ {
+		var next []resourceKey
+
+		for _, key := range frontier {
+			for neighbour := range edges[key] {
+				if _, ok := visited[neighbour]; ok || neighbour == start {
+					continue
+				}
+
+				visited[neighbour] = struct{}{}
+				next = append(next, neighbour)
+			}
+		}
+
+		frontier = next
+	}
+
+	return visited
+}
+
+func (c *Client) grant(userID, action string, resource resourceKey) {
+	if c.grants[userID] == nil {
+		c.grants[userID] = make(map[grant]struct{})
+	}
+
+	c.grants[userID][grant{action: action, resource: resource}] = struct{}{}
+}
+
+func (c *Client) requireResource(method string, resource *common.Origin) error {
+	if _, ok := c.resources[keyOf(resource)]; !ok {
+		return errorf(method, authorize.ErrNotFound, "resource %s/%s not found", resource.GetType(), resource.GetId())
+	}
+
+	return nil
+}
+
+func (c *Client) requireAction(method, name string) error {
+	if _, ok := c.actions[name]; !ok {
+		return errorf(method, authorize.ErrNotFound, "action %s not found", name)
+	}
+
+	return nil
+}
+
+func (c *Client) filterResources(keep func(resourceKey) bool) []*common.Origin {
+	resources := []*common.Origin{}
+
+	for key, resource := range c.resources {
+		if keep(key) {
+			resources = append(resources, clone(resource))
+		}
+	}
+
+	slices.SortFunc(resources, compareOrigins)
+
+	return resources
+}
+
+func (c *Client) actionResources(userID string, keep func(resourceKey) bool) []*grpcapi.ActionResource {
+	actionResources := []*grpcapi.ActionResource{}
+
+	for g := range c.grants[userID] {
+		if keep(g.resource) {
+			actionResources = append(actionResources, &grpcapi.ActionResource{
+				ActionName: g.action,
+				Resource:   clone(c.resources[g.resource]),
+			})
+		}
+	}
+
+	slices.SortFunc(actionResources, func(a, b *grpcapi.ActionResource) int {
+		return cmp.Or(
+			cmp.Compare(a.GetActionName(), b.GetActionName()),
+			compareOrigins(a.GetResource(), b.GetResource()),
+		)
+	})
+
+	return actionResources
+}
+
+func (c *Client) actionsByName(names []string) []*grpcapi.Action {
+	actions := []*grpcapi.Action{}
+
+	for _, name := range names {
+		if action, ok := c.actions[name]; ok {
+			actions = append(actions, cloneAction(action))
+		}
+	}
+
+	slices.SortFunc(actions, func(a, b *grpcapi.Action) int {
+		return cmp.Compare(a.GetName(), b.GetName())
+	})
+
+	return actions
+}
+
+func clone(resource *common.Origin) *common.Origin {
+	if resource == nil {
+		return nil
+	}
+
+	return &common.Origin{
+		Id:       resource.GetId(),
+		Type:     resource.GetType(),
+		Provider: resource.GetProvider(),
+	}
+}
+
+func cloneAction(action *grpcapi.Action) *grpcapi.Action {
+	return &grpcapi.Action{
+		Name: action.GetName(),
+		Type: action.GetType(),
+		Data: maps.Clone(action.GetData()),
+	}
+}
+
+func compareOrigins(a, b *common.Origin) int {
+	return cmp.Or(
+		cmp.Compare(a.GetType(), b.GetType()),
+		cmp.Compare(a.GetId(), b.GetId()),
+	)
+}
+
+func errorf(method string, kind error, format string, args ...any) error {
+	return authorize.NewError(method, kind, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,131 @@
+package fake_test
+
+import (
+	"context"
+	"testing"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/fake"
+	grpcapi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	company = &common.Origin{Id: "company", Type: "company", Provider: "hierarchy"}
+	site    = &common.Origin{Id: "site", Type: "site", Provider: "hierarchy"}
+	asset   = &common.Origin{Id: "asset", Type: "asset", Provider: "hierarchy"}
+	other   = &common.Origin{Id: "other", Type: "site", Provider: "hierarchy"}
+)
+
+func seed(t *testing.T) *fake.Client {
+	ctx := context.Background()
+	c := fake.New()
+
+	require.NoError(t, c.AddResources(ctx, []*common.Origin{company, site, asset, other}))
+	require.NoError(t, c.AddResourceRelation(ctx, site, company))
+	require.NoError(t, c.AddResourceRelation(ctx, asset, site))
+	require.NoError(t, c.AddAction(ctx, &grpcapi.Action{Name: "read"}))
+	require.NoError(t, c.AddAction(ctx, &grpcapi.Action{Name: "write"}))
+
+	return c
+}
+
+func Test_IsAuthorizedIsInherited(t *testing.T) {
+	ctx := context.Background()
+	c := seed(t)
+
+	require.NoError(t, c.ApplyUserAction(ctx, "user", "read", site))
+
+	for _, tc := range []struct {
+		resource *common.Origin
+		action   string
+		ok       bool
+		reason   string
+	}{
+		{resource: asset, action: "read", ok: true},
+		{resource: site, action: "read", ok: true},
+		{resource: company, action: "read", reason: authorize.ReasonAccessDenied},
+		{resource: asset, action: "write", reason: authorize.ReasonAccessDenied},
+		{resource: &common.Origin{Id: "missing", Type: "asset"}, action: "read", reason: authorize.ReasonResourceNotFound},
+	} {
+		ok, reason, err := c.IsAuthorizedWithReason(ctx, "user", tc.action, tc.resource)
+		require.NoError(t, err)
+		assert.Equal(t, tc.ok, ok, "%s on %s", tc.action, tc.resource.Id)
+		assert.Equal(t, tc.reason, reason, "%s on %s", tc.action, tc.resource.Id)
+	}
+
+	resources, oks, err := c.IsAuthorizedBulk(ctx, "user", "read", []*common.Origin{company, asset})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"company", "asset"}, []string{resources[0].Id, resources[1].Id})
+	assert.Equal(t, []bool{false, true}, oks)
+
+	userIDs, err := c.GetUserIDsWithAccessToResource(ctx, asset)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user"}, userIDs)
+
+	require.NoError(t, c.RemoveResourceRelation(ctx, asset, site))
+
+	ok, err := c.IsAuthorized(ctx, "user", "read", asset)
+	require.NoError(t, err)
+	assert.False(t, ok, "The grant is no longer inherited once the relation is removed")
+}
+
+func Test_Roles(t *testing.T) {
+	ctx := context.Background()
+	c := seed(t)
+
+	require.NoError(t, c.AddUserRole(ctx, &grpcapi.UserRole{Name: "editor", Actions: []string{"read", "write"}}))
+	require.NoError(t, c.ApplyRolesForUserOnResources(ctx, "user", []string{"editor"}, []*common.Origin{company}))
+
+	actions, err := c.GetUserActions(ctx, "user")
+	require.NoError(t, err)
+	require.Len(t, actions, 2)
+	assert.Equal(t, "read", actions[0].Name)
+	assert.Equal(t, "write", actions[1].Name)
+
+	resources, err := c.GetResourcesByUserAction(ctx, "user", "write", "site")
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "site", resources[0].Id)
+
+	require.NoError(t, c.RemoveAction(ctx, "write"))
+
+	role, err := c.GetUserRole(ctx, "editor")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, role.Actions)
+}
+
+func Test_Hierarchy(t *testing.T) {
+	ctx := context.Background()
+	c := seed(t)
+
+	resources, err := c.GetResourcesByOriginAndType(ctx, company, "", 1)
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "site", resources[0].Id)
+
+	resources, err = c.GetResourcesByOriginAndType(ctx, company, "asset", 0)
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "asset", resources[0].Id)
+
+	children, err := c.GetResourceChildren(ctx, site, "")
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, "asset", children[0].Id)
+
+	err = c.AddResourceRelation(ctx, company, asset)
+	assert.ErrorIs(t, err, authorize.ErrInvalidArgument, "Cycles are rejected")
+
+	require.NoError(t, c.RemoveResource(ctx, site))
+
+	parents, err := c.GetResourceParents(ctx, asset, "")
+	require.NoError(t, err)
+	assert.Empty(t, parents)
+
+	_, err = c.GetResource(ctx, "site", "site")
+	assert.ErrorIs(t, err, authorize.ErrNotFound)
+}
@@ -35,27 +35,37 @@ func NewServerOnHostPort(host, port string) (server *AuthorizeServer, err error)
 	authorize.RegisterAuthorizeServer(server.grpc, server)
 	reflection.Register(server.grpc)
 
-	server.listener, err = net.Listen("tcp", net.JoinHostPort(host, port))
+	server.listener, server.done, err = serve(server.grpc, host, port)
+
+	return
+}
+
+func serve(server *grpc.Server, host, port string) (net.Listener, chan error, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
 	if err != nil {
-		return
+		return nil, nil, err
 	}
 
-	server.done = make(chan error)
+	done := make(chan error)
 	go func() {
-		server.done <- server.grpc.Serve(server.listener)
+		done <- server.Serve(listener)
 	}()
 
-	return
+	return listener, done, nil
 }
 
-func (s *AuthorizeServer) HostPort() (string, string) {
-	host, port, err := net.SplitHostPort(s.listener.Addr().String())
+func hostPort(listener net.Listener) (string, string) {
+	host, port, err := net.SplitHostPort(listener.Addr().String())
 	if err != nil {
 		panic(err)
 	}
 	return host, port
 }
 
+func (s *AuthorizeServer) HostPort() (string, string) {
+	return hostPort(s.listener)
+}
+
 func (s *AuthorizeServer) AssertExpectations(t *testing.T) {
 	s.grpc.Stop()
 	require.NoError(t, <-s.done)
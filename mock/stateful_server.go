@@ -0,0 +1,339 @@
+package mock
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/SKF/go-enlight-authorizer/fake"
+	authorize "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+)
+
+// StatefulAuthorizeServer is an authorize.AuthorizeServer backed by an
+// in-memory fake.Client instead of scripted expectations. The Store can be
+// used to seed or inspect the state directly.
+type StatefulAuthorizeServer struct {
+	Store *fake.Client
+
+	grpc     *grpc.Server
+	opts     []grpc.ServerOption
+	done     chan error
+	listener net.Listener
+
+	mu           sync.Mutex
+	clientStates []*authorize.LogClientStateInput
+}
+
+var _ authorize.AuthorizeServer = &StatefulAuthorizeServer{}
+
+func NewStatefulServer(opts ...grpc.ServerOption) (*StatefulAuthorizeServer, error) {
+	return NewStatefulServerOnHostPort("localhost", "0", opts...)
+}
+
+func NewStatefulServerOnHostPort(host, port string, opts ...grpc.ServerOption) (server *StatefulAuthorizeServer, err error) {
+	server = &StatefulAuthorizeServer{
+		Store: fake.New(),
+		opts:  opts,
+	}
+
+	err = server.start(host, port)
+
+	return
+}
+
+func (s *StatefulAuthorizeServer) start(host, port string) (err error) {
+	s.grpc = grpc.NewServer(s.opts...)
+
+	authorize.RegisterAuthorizeServer(s.grpc, s)
+	reflection.Register(s.grpc)
+
+	s.listener, s.done, err = serve(s.grpc, host, port)
+
+	return
+}
+
+func (s *StatefulAuthorizeServer) HostPort() (string, string) {
+	return hostPort(s.listener)
+}
+
+func (s *StatefulAuthorizeServer) Stop(t *testing.T) {
+	s.grpc.Stop()
+	require.NoError(t, <-s.done)
+}
+
+// Restart stops the server, closing every connection, and starts it again on
+// the same host and port once downtime has passed. The state of the Store and
+// the client states are kept.
+func (s *StatefulAuthorizeServer) Restart(t *testing.T, downtime time.Duration) {
+	host, port := s.HostPort()
+
+	s.Stop(t)
+	time.Sleep(downtime)
+	require.NoError(t, s.start(host, port))
+}
+
+// ClientStates returns the client states reported through LogClientState.
+func (s *StatefulAuthorizeServer) ClientStates() []*authorize.LogClientStateInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*authorize.LogClientStateInput(nil), s.clientStates...)
+}
+
+func (s *StatefulAuthorizeServer) DeepPing(ctx context.Context, void *common.Void) (*common.PrimitiveString, error) {
+	return &common.PrimitiveString{Value: ""}, s.Store.DeepPing(ctx)
+}
+
+func (s *StatefulAuthorizeServer) LogClientState(ctx context.Context, in *authorize.LogClientStateInput) (*common.Void, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clientStates = append(s.clientStates, &authorize.LogClientStateInput{
+		State:    in.GetState(),
+		Hostname: in.GetHostname(),
+	})
+
+	return &common.Void{}, nil
+}
+
+func (s *StatefulAuthorizeServer) IsAuthorized(ctx context.Context, in *authorize.IsAuthorizedInput) (*authorize.IsAuthorizedOutput, error) {
+	ok, err := s.Store.IsAuthorized(ctx, in.GetUserId(), in.GetAction(), in.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.IsAuthorizedOutput{Ok: ok}, nil
+}
+
+func (s *StatefulAuthorizeServer) IsAuthorizedBulk(ctx context.Context, in *authorize.IsAuthorizedBulkInput) (*authorize.IsAuthorizedBulkOutput, error) {
+	resources, oks, err := s.Store.IsAuthorizedBulk(ctx, in.GetUserId(), in.GetAction(), in.GetResources())
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*authorize.IsAuthorizedOutItem, len(resources))
+	for i := range resources {
+		responses[i] = &authorize.IsAuthorizedOutItem{
+			ResourceId: resources[i].GetId(),
+			Ok:         oks[i],
+			Resource:   resources[i],
+		}
+	}
+
+	return &authorize.IsAuthorizedBulkOutput{Responses: responses}, nil
+}
+
+func (s *StatefulAuthorizeServer) IsAuthorizedByEndpoint(ctx context.Context, in *authorize.IsAuthorizedByEndpointInput) (*authorize.IsAuthorizedByEndpointOutput, error) {
+	ok, err := s.Store.IsAuthorizedByEndpoint(ctx, in.GetApi(), in.GetMethod(), in.GetEndpoint(), in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.IsAuthorizedByEndpointOutput{Ok: ok}, nil
+}
+
+func (s *StatefulAuthorizeServer) IsAuthorizedWithReason(ctx context.Context, in *authorize.IsAuthorizedInput) (*authorize.IsAuthorizedWithReasonOutput, error) {
+	ok, reason, err := s.Store.IsAuthorizedWithReason(ctx, in.GetUserId(), in.GetAction(), in.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.IsAuthorizedWithReasonOutput{Ok: ok, Reason: reason}, nil
+}
+
+func (s *StatefulAuthorizeServer) AddResource(ctx context.Context, in *authorize.AddResourceInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.AddResource(ctx, in.GetResource())
+}
+
+func (s *StatefulAuthorizeServer) RemoveResource(ctx context.Context, in *authorize.RemoveResourceInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.RemoveResource(ctx, in.GetResource())
+}
+
+func (s *StatefulAuthorizeServer) GetResource(ctx context.Context, in *authorize.GetResourceInput) (*authorize.GetResourceOutput, error) {
+	resource, err := s.Store.GetResource(ctx, in.GetId(), in.GetOriginType())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetResourceOutput{Resource: resource}, nil
+}
+
+func (s *StatefulAuthorizeServer) AddResources(ctx context.Context, in *authorize.AddResourcesInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.AddResources(ctx, in.GetResource())
+}
+
+func (s *StatefulAuthorizeServer) RemoveResources(ctx context.Context, in *authorize.RemoveResourcesInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.RemoveResources(ctx, in.GetResource())
+}
+
+func (s *StatefulAuthorizeServer) GetResourcesByUserAction(ctx context.Context, in *authorize.GetResourcesByUserActionInput) (*authorize.GetResourcesByUserActionOutput, error) {
+	resources, err := s.Store.GetResourcesByUserAction(ctx, in.GetUserId(), in.GetAction(), in.GetResourceType())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetResourcesByUserActionOutput{Resources: resources}, nil
+}
+
+func (s *StatefulAuthorizeServer) GetResourcesByType(ctx context.Context, in *authorize.GetResourcesByTypeInput) (*authorize.GetResourcesByTypeOutput, error) {
+	resources, err := s.Store.GetResourcesByType(ctx, in.GetResourceType())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetResourcesByTypeOutput{Resources: resources}, nil
+}
+
+func (s *StatefulAuthorizeServer) GetResourceParents(ctx context.Context, in *authorize.GetResourceParentsInput) (*authorize.GetResourcesOutput, error) {
+	resources, err := s.Store.GetResourceParents(ctx, in.GetResource(), in.GetParentOriginType())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetResourcesOutput{Resources: resources}, nil
+}
+
+func (s *StatefulAuthorizeServer) GetResourceChildren(ctx context.Context, in *authorize.GetResourceChildrenInput) (*authorize.GetResourcesOutput, error) {
+	resources, err := s.Store.GetResourceChildren(ctx, in.GetResource(), in.GetChildOriginType())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetResourcesOutput{Resources: resources}, nil
+}
+
+func (s *StatefulAuthorizeServer) GetActionsByUserRole(ctx context.Context, in *authorize.GetActionsByUserRoleInput) (*authorize.GetActionsByUserRoleOutput, error) {
+	actions, err := s.Store.GetActionsByUserRole(ctx, in.GetUserRole())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetActionsByUserRoleOutput{Actions: actions}, nil
+}
+
+func (s *StatefulAuthorizeServer) GetResourcesAndActionsByUser(ctx context.Context, in *authorize.GetResourcesAndActionsByUserInput) (*authorize.GetResourcesAndActionsByUserOutput, error) {
+	data, err := s.Store.GetResourcesAndActionsByUser(ctx, in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetResourcesAndActionsByUserOutput{Data: data}, nil
+}
+
+func (s *StatefulAuthorizeServer) GetResourcesAndActionsByUserAndResource(ctx context.Context, in *authorize.GetResourcesAndActionsByUserAndResourceInput) (*authorize.GetResourcesAndActionsByUserAndResourceOutput, error) {
+	data, err := s.Store.GetResourcesAndActionsByUserAndResource(ctx, in.GetUserId(), in.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetResourcesAndActionsByUserAndResourceOutput{Data: data}, nil
+}
+
+func (s *StatefulAuthorizeServer) GetResourcesByOriginAndType(ctx context.Context, in *authorize.GetResourcesByOriginAndTypeInput) (*authorize.GetResourcesByOriginAndTypeOutput, error) {
+	resources, err := s.Store.GetResourcesByOriginAndType(ctx, in.GetResource(), in.GetResourceType(), in.GetDepth())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetResourcesByOriginAndTypeOutput{Resources: resources}, nil
+}
+
+func (s *StatefulAuthorizeServer) GetResourcesWithActionsAccess(ctx context.Context, in *authorize.GetResourcesWithActionsAccessInput) (*authorize.GetResourcesWithActionsAccessOutput, error) {
+	resources, err := s.Store.GetResourcesWithActionsAccess(ctx, in.GetActions(), in.GetResourceType(), in.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetResourcesWithActionsAccessOutput{Resources: resources}, nil
+}
+
+func (s *StatefulAuthorizeServer) GetUserIDsWithAccessToResource(ctx context.Context, in *authorize.GetUserIDsWithAccessToResourceInput) (*authorize.GetUserIDsWithAccessToResourceOutput, error) {
+	userIDs, err := s.Store.GetUserIDsWithAccessToResource(ctx, in.GetResource())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetUserIDsWithAccessToResourceOutput{UserIds: userIDs}, nil
+}
+
+func (s *StatefulAuthorizeServer) AddResourceRelation(ctx context.Context, in *authorize.AddResourceRelationInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.AddResourceRelation(ctx, in.GetResource(), in.GetParent())
+}
+
+func (s *StatefulAuthorizeServer) RemoveResourceRelation(ctx context.Context, in *authorize.RemoveResourceRelationInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.RemoveResourceRelation(ctx, in.GetResource(), in.GetParent())
+}
+
+func (s *StatefulAuthorizeServer) AddResourceRelations(ctx context.Context, in *authorize.AddResourceRelationsInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.AddResourceRelations(ctx, in)
+}
+
+func (s *StatefulAuthorizeServer) RemoveResourceRelations(ctx context.Context, in *authorize.RemoveResourceRelationsInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.RemoveResourceRelations(ctx, in)
+}
+
+func (s *StatefulAuthorizeServer) ApplyUserAction(ctx context.Context, in *authorize.ApplyUserActionInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.ApplyUserAction(ctx, in.GetUserId(), in.GetAction(), in.GetResource())
+}
+
+func (s *StatefulAuthorizeServer) ApplyRolesForUserOnResources(ctx context.Context, in *authorize.ApplyRolesForUserOnResourcesInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.ApplyRolesForUserOnResources(ctx, in.GetUserId(), in.GetRoles(), in.GetResources())
+}
+
+func (s *StatefulAuthorizeServer) GetUserActions(ctx context.Context, in *authorize.GetUserActionsInput) (*authorize.GetUserActionsOutput, error) {
+	actions, err := s.Store.GetUserActions(ctx, in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetUserActionsOutput{Actions: actions}, nil
+}
+
+func (s *StatefulAuthorizeServer) RemoveUserAction(ctx context.Context, in *authorize.RemoveUserActionInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.RemoveUserAction(ctx, in.GetUserId(), in.GetAction(), in.GetResource())
+}
+
+func (s *StatefulAuthorizeServer) AddUserRole(ctx context.Context, in *authorize.UserRole) (*common.Void, error) {
+	return &common.Void{}, s.Store.AddUserRole(ctx, in)
+}
+
+func (s *StatefulAuthorizeServer) GetUserRole(ctx context.Context, in *authorize.GetUserRoleInput) (*authorize.UserRole, error) {
+	return s.Store.GetUserRole(ctx, in.GetRoleName())
+}
+
+func (s *StatefulAuthorizeServer) RemoveUserRole(ctx context.Context, in *authorize.RemoveUserRoleInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.RemoveUserRole(ctx, in.GetRoleName())
+}
+
+func (s *StatefulAuthorizeServer) AddAction(ctx context.Context, in *authorize.AddActionInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.AddAction(ctx, in.GetAction())
+}
+
+func (s *StatefulAuthorizeServer) RemoveAction(ctx context.Context, in *authorize.RemoveActionInput) (*common.Void, error) {
+	return &common.Void{}, s.Store.RemoveAction(ctx, in.GetName())
+}
+
+func (s *StatefulAuthorizeServer) GetAction(ctx context.Context, in *authorize.GetActionInput) (*authorize.GetActionOutput, error) {
+	action, err := s.Store.GetAction(ctx, in.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetActionOutput{Action: action}, nil
+}
+
+func (s *StatefulAuthorizeServer) GetAllActions(ctx context.Context, in *common.Void) (*authorize.GetAllActionsOutput, error) {
+	actions, err := s.Store.GetAllActions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorize.GetAllActionsOutput{Actions: actions}, nil
+}
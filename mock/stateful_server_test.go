@@ -0,0 +1,60 @@
+package mock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authorize "github.com/SKF/go-enlight-authorizer/client"
+	"github.com/SKF/go-enlight-authorizer/mock"
+	grpcapi "github.com/SKF/proto/v2/authorize"
+	"github.com/SKF/proto/v2/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func Test_StatefulServer(t *testing.T) {
+	server, err := mock.NewStatefulServer()
+	require.NoError(t, err)
+	defer server.Stop(t)
+
+	host, port := server.HostPort()
+
+	client := authorize.CreateClient()
+	require.NoError(t, client.Dial(context.Background(), host, port, grpc.WithTransportCredentials(insecure.NewCredentials())))
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	site := &common.Origin{Id: "site", Type: "site", Provider: "hierarchy"}
+	asset := &common.Origin{Id: "asset", Type: "asset", Provider: "hierarchy"}
+
+	require.NoError(t, client.AddResources(ctx, []*common.Origin{site, asset}))
+	require.NoError(t, client.AddResourceRelation(ctx, asset, site))
+	require.NoError(t, client.AddAction(ctx, &grpcapi.Action{Name: "read"}))
+	require.NoError(t, client.ApplyUserAction(ctx, "user", "read", site))
+
+	ok, err := client.IsAuthorized(ctx, "user", "read", asset)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	resources, oks, err := client.IsAuthorizedBulk(ctx, "user", "read", []*common.Origin{asset, site})
+	require.NoError(t, err)
+	assert.Equal(t, "asset", resources[0].GetId())
+	assert.Equal(t, "hierarchy", resources[0].GetProvider())
+	assert.Equal(t, []bool{true, true}, oks)
+
+	require.NoError(t, client.RemoveUserAction(ctx, "user", "read", site))
+
+	ok, reason, err := client.IsAuthorizedWithReason(ctx, "user", "read", asset)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, authorize.ReasonAccessDenied, reason)
+
+	_, err = client.GetResource(ctx, "missing", "asset")
+	assert.ErrorIs(t, err, authorize.ErrNotFound)
+}
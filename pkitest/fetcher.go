@@ -0,0 +1,101 @@
+package pkitest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+)
+
+// Fetcher is a credentials fetcher returning credentials which can be rotated
+// on demand. It stores a DataStore per version stage, and reports rotations
+// as a credentialsmanager.Watcher, which makes transport credentials reload
+// the rotated credentials on the next handshake.
+type Fetcher struct {
+	mu     sync.Mutex
+	stages map[string]credentialsmanager.DataStore
+	err    error
+	calls  int
+
+	generation atomic.Uint64
+}
+
+var _ credentialsmanager.StagedFetcher = &Fetcher{}
+var _ credentialsmanager.Watcher = &Fetcher{}
+
+// NewFetcher creates a fetcher returning ds as the current version. The zero
+// Fetcher has no versions.
+func NewFetcher(ds credentialsmanager.DataStore) *Fetcher {
+	return &Fetcher{
+		stages: map[string]credentialsmanager.DataStore{
+			credentialsmanager.VersionStageCurrent: ds,
+		},
+	}
+}
+
+// GetDataStore returns the current version, ignoring the secret name.
+func (f *Fetcher) GetDataStore(ctx context.Context, secretsName string) (*credentialsmanager.DataStore, error) {
+	return f.GetDataStoreVersion(ctx, secretsName, credentialsmanager.VersionStageCurrent)
+}
+
+// GetDataStoreVersion returns the given version stage, ignoring the secret
+// name.
+func (f *Fetcher) GetDataStoreVersion(ctx context.Context, secretsName, versionStage string) (*credentialsmanager.DataStore, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	ds, ok := f.stages[versionStage]
+	if !ok {
+		return nil, fmt.Errorf("no version of '%s' is labelled %s", secretsName, versionStage)
+	}
+
+	ds.VersionStage = versionStage
+
+	return &ds, nil
+}
+
+// Rotate replaces the current version.
+func (f *Fetcher) Rotate(ds credentialsmanager.DataStore) {
+	f.RotateStage(credentialsmanager.VersionStageCurrent, ds)
+}
+
+// RotateStage replaces the given version stage.
+func (f *Fetcher) RotateStage(versionStage string, ds credentialsmanager.DataStore) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stages == nil {
+		f.stages = map[string]credentialsmanager.DataStore{}
+	}
+
+	f.stages[versionStage] = ds
+	f.generation.Add(1)
+}
+
+// Fail makes every fetch fail with err, until called with nil.
+func (f *Fetcher) Fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.err = err
+}
+
+// Calls returns the number of fetches.
+func (f *Fetcher) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+// Generation increases on every rotation.
+func (f *Fetcher) Generation() uint64 {
+	return f.generation.Load()
+}
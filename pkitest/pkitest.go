@@ -0,0 +1,343 @@
+// Package pkitest generates ephemeral certificate authorities and
+// certificates for tests, returned as credentialsmanager.DataStore, and
+// provides a credentials fetcher whose credentials can be rotated on demand.
+//
+// A CA issues server certificates, allowing server authentication and valid
+// for localhost by default, and client certificates, allowing client
+// authentication. Keys are ECDSA P-256 by default, which are cheap to
+// generate, and RSA on request.
+package pkitest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+)
+
+const (
+	// DefaultValidity is how long certificates are valid by default.
+	DefaultValidity = 365 * 24 * time.Hour
+	// DefaultCAValidity is how long CAs are valid by default, which is longer
+	// than any certificate they issue by default.
+	DefaultCAValidity = 20 * DefaultValidity
+)
+
+// KeyType is the type of key generated for a certificate.
+type KeyType int
+
+const (
+	ECDSA KeyType = iota
+	RSA
+)
+
+type config struct {
+	keyType    KeyType
+	key        crypto.Signer
+	notBefore  time.Time
+	notAfter   time.Time
+	commonName string
+	dnsNames   []string
+	ips        []net.IP
+	uris       []string
+	usages     []x509.ExtKeyUsage
+}
+
+// Option configures a CA or a certificate.
+type Option func(*config)
+
+// WithKeyType sets the type of key generated, ECDSA by default.
+func WithKeyType(keyType KeyType) Option {
+	return func(c *config) {
+		c.keyType = keyType
+	}
+}
+
+// WithKey uses the given key rather than generating one, e.g. to avoid
+// generating RSA keys in every test.
+func WithKey(key crypto.Signer) Option {
+	return func(c *config) {
+		c.key = key
+	}
+}
+
+// WithValidity sets the validity window of the certificate.
+func WithValidity(notBefore, notAfter time.Time) Option {
+	return func(c *config) {
+		c.notBefore, c.notAfter = notBefore, notAfter
+	}
+}
+
+// WithValidFor makes the certificate valid from now for d.
+func WithValidFor(d time.Duration) Option {
+	return func(c *config) {
+		c.notBefore, c.notAfter = time.Now(), time.Now().Add(d)
+	}
+}
+
+// WithCommonName sets the common name of the subject of the certificate.
+func WithCommonName(name string) Option {
+	return func(c *config) {
+		c.commonName = name
+	}
+}
+
+// WithDNSNames sets the DNS SANs of the certificate, which are localhost for
+// server certificates by default.
+func WithDNSNames(names ...string) Option {
+	return func(c *config) {
+		c.dnsNames = names
+	}
+}
+
+// WithIPAddresses sets the IP SANs of the certificate.
+func WithIPAddresses(ips ...net.IP) Option {
+	return func(c *config) {
+		c.ips = ips
+	}
+}
+
+// WithURIs sets the URI SANs of the certificate, e.g. a SPIFFE ID.
+func WithURIs(uris ...string) Option {
+	return func(c *config) {
+		c.uris = uris
+	}
+}
+
+// WithExtKeyUsage overrides the extended key usage of the certificate. No
+// usages allow any use.
+func WithExtKeyUsage(usages ...x509.ExtKeyUsage) Option {
+	return func(c *config) {
+		c.usages = usages
+	}
+}
+
+func newConfig(validity time.Duration, opts []Option) *config {
+	now := time.Now()
+	c := &config{
+		// Backdated to tolerate clock skew.
+		notBefore: now.Add(-time.Minute),
+		notAfter:  now.Add(validity),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *config) template() (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: c.commonName},
+		NotBefore:    c.notBefore,
+		NotAfter:     c.notAfter,
+		DNSNames:     c.dnsNames,
+		IPAddresses:  c.ips,
+		ExtKeyUsage:  c.usages,
+	}
+
+	for _, uri := range c.uris {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI SAN '%s': %w", uri, err)
+		}
+
+		template.URIs = append(template.URIs, u)
+	}
+
+	return template, nil
+}
+
+func (c *config) privateKey() (crypto.Signer, error) {
+	if c.key != nil {
+		return c.key, nil
+	}
+
+	switch c.keyType {
+	case ECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case RSA:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+
+	return nil, fmt.Errorf("unknown key type %d", c.keyType)
+}
+
+// CA is a certificate authority issuing certificates.
+type CA struct {
+	Certificate *x509.Certificate
+	Key         crypto.Signer
+	// PEM is the PEM encoded certificate, as in DataStore.CA.
+	PEM []byte
+}
+
+// NewCA creates a self-signed CA, valid for DefaultCAValidity by default.
+func NewCA(opts ...Option) (*CA, error) {
+	c := newConfig(DefaultCAValidity, opts)
+	if c.commonName == "" {
+		c.commonName = "pkitest CA"
+	}
+
+	template, err := c.template()
+	if err != nil {
+		return nil, err
+	}
+
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+
+	key, err := c.privateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{Certificate: cert, Key: key, PEM: encodeCertificate(der)}, nil
+}
+
+// Issue issues a certificate, valid for DefaultValidity and allowing any use
+// by default.
+func (ca *CA) Issue(opts ...Option) (credentialsmanager.DataStore, error) {
+	c := newConfig(DefaultValidity, opts)
+
+	template, err := c.template()
+	if err != nil {
+		return credentialsmanager.DataStore{}, err
+	}
+
+	key, err := c.privateKey()
+	if err != nil {
+		return credentialsmanager.DataStore{}, err
+	}
+
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, key.Public(), ca.Key)
+	if err != nil {
+		return credentialsmanager.DataStore{}, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return credentialsmanager.DataStore{}, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	return credentialsmanager.DataStore{
+		CA:  ca.PEM,
+		Crt: encodeCertificate(der),
+		Key: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// Server issues a server certificate, valid for localhost by default.
+func (ca *CA) Server(opts ...Option) (credentialsmanager.DataStore, error) {
+	defaults := []Option{
+		WithCommonName("server"),
+		WithDNSNames("localhost"),
+		WithExtKeyUsage(x509.ExtKeyUsageServerAuth),
+	}
+
+	return ca.Issue(append(defaults, opts...)...)
+}
+
+// Client issues a client certificate.
+func (ca *CA) Client(opts ...Option) (credentialsmanager.DataStore, error) {
+	defaults := []Option{
+		WithCommonName("client"),
+		WithExtKeyUsage(x509.ExtKeyUsageClientAuth),
+	}
+
+	return ca.Issue(append(defaults, opts...)...)
+}
+
+// CertPool returns a pool holding the CA certificate.
+func (ca *CA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+
+	return pool
+}
+
+// ServerTLSConfig returns the TLS configuration of a server using the
+// certificate of ds and requiring client certificates issued by its CA.
+func ServerTLSConfig(ds credentialsmanager.DataStore) (*tls.Config, error) {
+	certificate, clientCAs, err := load(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS13,
+		NextProtos:   []string{"h2"},
+	}, nil
+}
+
+// ClientTLSConfig returns the TLS configuration of a client using the
+// certificate of ds and trusting servers named serverName issued by its CA.
+func ClientTLSConfig(ds credentialsmanager.DataStore, serverName string) (*tls.Config, error) {
+	certificate, rootCAs, err := load(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		ServerName:   serverName,
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      rootCAs,
+		NextProtos:   []string{"h2"},
+	}, nil
+}
+
+func load(ds credentialsmanager.DataStore) (tls.Certificate, *x509.CertPool, error) {
+	certificate, err := tls.X509KeyPair(ds.Crt, ds.Key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ds.CA) {
+		return tls.Certificate{}, nil, errors.New("failed to add CA certificate")
+	}
+
+	return certificate, pool, nil
+}
+
+func encodeCertificate(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,108 @@
+package pkitest_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SKF/go-enlight-authorizer/client/credentialsmanager"
+	"github.com/SKF/go-enlight-authorizer/pkitest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CA(t *testing.T) {
+	ca, err := pkitest.NewCA()
+	require.NoError(t, err)
+	assert.IsType(t, &ecdsa.PrivateKey{}, ca.Key)
+
+	server, err := ca.Server(pkitest.WithKeyType(pkitest.RSA))
+	require.NoError(t, err)
+
+	info, err := server.ValidateUsage(x509.ExtKeyUsageServerAuth)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"localhost"}, info.DNSNames)
+
+	keyPair, err := tls.X509KeyPair(server.Crt, server.Key)
+	require.NoError(t, err)
+	assert.IsType(t, &rsa.PrivateKey{}, keyPair.PrivateKey)
+
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second)
+	client, err := ca.Client(pkitest.WithValidity(time.Now().Add(-time.Minute), notAfter), pkitest.WithURIs("spiffe://example.org/client"))
+	require.NoError(t, err)
+
+	info, err = client.Validate()
+	require.NoError(t, err)
+	assert.Equal(t, notAfter.UTC(), info.NotAfter)
+
+	_, err = client.ValidateUsage(x509.ExtKeyUsageServerAuth)
+	assert.ErrorIs(t, err, credentialsmanager.ErrKeyUsage)
+}
+
+func Test_TLSConfigs(t *testing.T) {
+	ca, err := pkitest.NewCA()
+	require.NoError(t, err)
+
+	server, err := ca.Server()
+	require.NoError(t, err)
+
+	client, err := ca.Client()
+	require.NoError(t, err)
+
+	serverConfig, err := pkitest.ServerTLSConfig(server)
+	require.NoError(t, err)
+
+	clientConfig, err := pkitest.ClientTLSConfig(client, "localhost")
+	require.NoError(t, err)
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	go func() {
+		defer serverConn.Close()
+		_ = tls.Server(serverConn, serverConfig).Handshake()
+	}()
+
+	require.NoError(t, tls.Client(clientConn, clientConfig).Handshake())
+}
+
+func Test_Fetcher(t *testing.T) {
+	ctx := context.Background()
+	first := credentialsmanager.DataStore{Crt: []byte("first")}
+
+	f := pkitest.NewFetcher(first)
+	ds, err := f.GetDataStore(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(ds.Crt))
+	assert.Equal(t, credentialsmanager.VersionStageCurrent, ds.VersionStage)
+	assert.Zero(t, f.Generation())
+
+	f.Rotate(credentialsmanager.DataStore{Crt: []byte("second")})
+	f.RotateStage(credentialsmanager.VersionStagePrevious, first)
+	assert.Equal(t, uint64(2), f.Generation())
+
+	ds, err = f.GetDataStore(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(ds.Crt))
+
+	ds, err = f.GetDataStoreVersion(ctx, "secret", credentialsmanager.VersionStagePrevious)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(ds.Crt))
+
+	_, err = f.GetDataStoreVersion(ctx, "secret", credentialsmanager.VersionStagePending)
+	assert.Error(t, err)
+
+	fetchErr := errors.New("secrets manager is down")
+	f.Fail(fetchErr)
+	_, err = f.GetDataStore(ctx, "secret")
+	assert.ErrorIs(t, err, fetchErr)
+
+	assert.Equal(t, 5, f.Calls())
+}